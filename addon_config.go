@@ -2,29 +2,63 @@ package userConfig
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
-// AddonConfig is an additional ConfigFile
+// ErrCategoryExists is returned when renaming a category to one that's
+// already in the addon config
+var ErrCategoryExists = errors.New("category already exists")
+
+// AddonConfig is an additional ConfigFile, with its values in categories
 type AddonConfig struct {
 	Name   string                       `toml:"-"`
 	Path   string                       `toml:"-"`
 	Values map[string]map[string]string `toml:"-"`
+
+	// mu guards Values, so af can be used from more than one goroutine
+	// through its methods
+	mu       sync.RWMutex
+	readOnly bool
+	fs       FileSystem
+	// ext is the extension of the file, ".toml" by default
+	ext string
+	// bytesEncoding controls how SetBytes stores values, dateTimeLayouts
+	// and location how GetDateTime parses them
+	bytesEncoding   BytesEncoding
+	dateTimeLayouts []string
+	location        *time.Location
 }
 
 // NewAddonConfig generates a Additional Config struct
 func NewAddonConfig(name, path string) (*AddonConfig, error) {
-	af := &AddonConfig{Name: name, Path: path}
+	return newAddonConfig(name, path, DefaultNaming.AddonExt, false, OSFileSystem{})
+}
+
+func newAddonConfig(name, path, ext string, readOnly bool, fs FileSystem) (*AddonConfig, error) {
+	af := &AddonConfig{Name: name, Path: path, ext: ext, readOnly: readOnly, fs: fs}
 	af.Values = make(map[string]map[string]string)
+	// Before anything is written, so the name can't point outside path
+	if err := ValidateFileName(name); err != nil {
+		return af, &ConfigError{Op: "load", Path: af.GetFullPath(), Err: err}
+	}
 
 	// Check if file exists
-	//var f os.FileInfo
 	var err error
-	if _, err = os.Stat(af.GetFullPath()); os.IsNotExist(err) {
+	if _, err = af.fileSystem().ReadFile(af.GetFullPath()); os.IsNotExist(err) {
+		if readOnly {
+			return af, &ConfigError{Op: "load", Path: af.GetFullPath(), Err: fmt.Errorf("%w: %w", ErrNotExist, err)}
+		}
 		if err = af.Save(); err != nil {
 			return af, err
 		}
@@ -36,7 +70,13 @@ func NewAddonConfig(name, path string) (*AddonConfig, error) {
 	return af, nil
 }
 
-/** START of ConfigFile Interface Implementation **/
+// fileSystem returns what af's file is read and written with
+func (af *AddonConfig) fileSystem() FileSystem {
+	if af.fs == nil {
+		return OSFileSystem{}
+	}
+	return af.fs
+}
 
 // GetName returns the name of this config file
 func (af *AddonConfig) GetName() string {
@@ -50,59 +90,328 @@ func (af *AddonConfig) GetPath() string {
 
 // Load loads config files into the config
 func (af *AddonConfig) Load() error {
-	if strings.TrimSpace(af.Name) == "" || strings.TrimSpace(af.Path) == "" {
-		return errors.New("Invalid ConfigFile Name: " + af.GetFullPath())
+	if err := ValidateFileName(af.Name); err != nil {
+		return &ConfigError{Op: "load", Path: af.GetFullPath(), Err: err}
+	}
+	if strings.TrimSpace(af.Path) == "" {
+		return &ConfigError{Op: "load", Path: af.GetFullPath(), Err: ErrInvalidName}
 	}
 
-	// Config files end with .toml
-	tomlData, err := ioutil.ReadFile(af.GetFullPath())
+	// Addon files end with .toml, unless the NamingPolicy says otherwise
+	tomlData, err := af.fileSystem().ReadFile(af.GetFullPath())
 	if err != nil {
-		return err
+		return &ConfigError{Op: "load", Path: af.GetFullPath(), Err: err}
 	}
-	fmt.Println(tomlData)
-	// TODO: Figure out loading this into the struct
-	//if _, err := toml.Decode(string(tomlData), &af); err != nil {
-	//	return err
-	//}
+	// Each category is a table in the file, hand-edited ones can have
+	// native TOML values in them
+	raw := make(map[string]map[string]interface{})
+	if _, err := decodeTOML(string(tomlData), &raw); err != nil {
+		return &ConfigError{Op: "load", Path: af.GetFullPath(), Err: newParseError(af.GetFullPath(), err)}
+	}
+	values := make(map[string]map[string]string)
+	for category, vals := range raw {
+		values[category] = tomlValues(vals)
+	}
+	af.mu.Lock()
+	af.Values = values
+	af.mu.Unlock()
 	return nil
 }
 
 // Save writes the config to file(s)
 func (af *AddonConfig) Save() error {
+	if af.readOnly {
+		return &ConfigError{Op: "save", Path: af.GetFullPath(), Err: ErrReadOnly}
+	}
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	return af.save()
+}
+
+// save writes af to its file, af.mu has to be held
+func (af *AddonConfig) save() error {
 	buf := new(bytes.Buffer)
-	// TODO: Figure out writing struct to buf
-	//if err := toml.NewEncoder(buf).Encode(af); err != nil {
-	//	return err
-	//}
-	return ioutil.WriteFile(af.GetFullPath(), buf.Bytes(), 0644)
+	if err := toml.NewEncoder(buf).Encode(af.Values); err != nil {
+		return &ConfigError{Op: "save", Path: af.GetFullPath(), Err: err}
+	}
+	if err := af.fileSystem().WriteFile(af.GetFullPath(), buf.Bytes(), 0644); err != nil {
+		return &ConfigError{Op: "save", Path: af.GetFullPath(), Err: err}
+	}
+	return nil
 }
 
 // Set sets a key/value pair in af, if unable to save, revert to old value
 // (and return the error)
 func (af *AddonConfig) Set(category, k, v string) error {
-	if _, ok := af.Values[category]; !ok {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	return af.set(category, k, v)
+}
+
+// set is Set for callers that hold af.mu
+func (af *AddonConfig) set(category, k, v string) error {
+	if af.readOnly {
+		return af.keyError("set", category, k, ErrReadOnly)
+	}
+	_, hadCategory := af.Values[category]
+	if !hadCategory {
 		af.Values[category] = make(map[string]string)
 	}
-	oldVal := af.Values[category][k]
+	oldVal, existed := af.Values[category][k]
 	af.Values[category][k] = v
-	if err := af.Save(); err != nil {
-		af.Values[category][k] = oldVal
+	if err := af.save(); err != nil {
+		if existed {
+			af.Values[category][k] = oldVal
+		} else if hadCategory {
+			delete(af.Values[category], k)
+		} else {
+			delete(af.Values, category)
+		}
 		return err
 	}
 	return nil
 }
 
+// SetInt sets an integer value (as a string) in a category of af
+func (af *AddonConfig) SetInt(category, k string, v int) error {
+	return af.Set(category, k, strconv.Itoa(v))
+}
+
+// SetDateTime sets a DateTime value (as an RFC3339 string, keeping any
+// fraction of a second) in a category of af
+func (af *AddonConfig) SetDateTime(category, k string, v time.Time) error {
+	return af.Set(category, k, formatDateTime(v))
+}
+
+// SetArray sets a string slice value (as a string) in a category of af
+func (af *AddonConfig) SetArray(category, k string, v []string) error {
+	b, e := json.Marshal(v)
+	if e != nil {
+		return af.keyError("set", category, k, e)
+	}
+	return af.Set(category, k, string(b))
+}
+
+// SetBytes sets a binary value in a category of af, encoded so that any
+// bytes survive the round trip
+func (af *AddonConfig) SetBytes(category, k string, v []byte) error {
+	return af.Set(category, k, encodeBytes(v, af.bytesEncoding))
+}
+
+// SetBool sets a boolean value (as a string) in a category of af
+func (af *AddonConfig) SetBool(category, k string, v bool) error {
+	return af.Set(category, k, strconv.FormatBool(v))
+}
+
+// SetFloat sets a float value (as a string) in a category of af
+func (af *AddonConfig) SetFloat(category, k string, v float64) error {
+	return af.Set(category, k, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+// SetDuration sets a time.Duration value (as a string) in a category of af
+func (af *AddonConfig) SetDuration(category, k string, v time.Duration) error {
+	return af.Set(category, k, v.String())
+}
+
 // Get gets a key/value pair from af
 func (af *AddonConfig) Get(category, k string) string {
-	if _, ok := af.Values[category]; !ok {
-		return ""
+	v, _ := af.lookup(category, k)
+	return v
+}
+
+// lookup returns the value of k in category and whether it's set
+func (af *AddonConfig) lookup(category, k string) (string, bool) {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	return af.lookupLocked(category, k)
+}
+
+// lookupLocked is lookup for callers that hold af.mu
+func (af *AddonConfig) lookupLocked(category, k string) (string, bool) {
+	v, ok := af.Values[category][k]
+	return v, ok
+}
+
+// GetInt gets a key/value pair from af and return it as an integer
+// An error if it can't be converted
+func (af *AddonConfig) GetInt(category, k string) (int, error) {
+	v, err := strconv.Atoi(af.Get(category, k))
+	if err != nil {
+		return v, af.keyError("get", category, k, err)
+	}
+	return v, nil
+}
+
+// GetDateTime gets a key/value pair from af and returns it as a time.Time
+// Any of the accepted layouts can be used, see DefaultDateTimeLayouts
+// An error if it can't be converted
+func (af *AddonConfig) GetDateTime(category, k string) (time.Time, error) {
+	v, err := parseDateTime(af.Get(category, k), af.dateTimeLayouts, af.location)
+	if err != nil {
+		return v, af.keyError("get", category, k, err)
+	}
+	return v, nil
+}
+
+// GetArray gets a key/value pair from af and returns it as a string slice
+// An error if it can't be converted
+func (af *AddonConfig) GetArray(category, k string) ([]string, error) {
+	var ret []string
+	if err := json.Unmarshal([]byte(af.Get(category, k)), &ret); err != nil {
+		return ret, af.keyError("get", category, k, err)
 	}
-	return af.Values[category][k]
+	return ret, nil
+}
+
+// GetBytes gets a key/value pair from af and returns it as a byte slice
+func (af *AddonConfig) GetBytes(category, k string) []byte {
+	return decodeBytes(af.Get(category, k))
+}
+
+// GetBool gets a key/value pair from af and returns it as a bool
+// An error if it can't be converted
+func (af *AddonConfig) GetBool(category, k string) (bool, error) {
+	v, err := strconv.ParseBool(af.Get(category, k))
+	if err != nil {
+		return v, af.keyError("get", category, k, err)
+	}
+	return v, nil
+}
+
+// GetFloat gets a key/value pair from af and returns it as a float64
+// An error if it can't be converted
+func (af *AddonConfig) GetFloat(category, k string) (float64, error) {
+	v, err := strconv.ParseFloat(af.Get(category, k), 64)
+	if err != nil {
+		return v, af.keyError("get", category, k, err)
+	}
+	return v, nil
+}
+
+// GetDuration gets a key/value pair from af and returns it as a
+// time.Duration
+// An error if it can't be converted
+func (af *AddonConfig) GetDuration(category, k string) (time.Duration, error) {
+	v, err := time.ParseDuration(af.Get(category, k))
+	if err != nil {
+		return v, af.keyError("get", category, k, err)
+	}
+	return v, nil
+}
+
+// DeleteKey removes a key from a category of af, if unable to save, revert
+// (and return the error)
+func (af *AddonConfig) DeleteKey(category, k string) error {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	return af.deleteKey(category, k)
+}
+
+// deleteKey is DeleteKey for callers that hold af.mu
+func (af *AddonConfig) deleteKey(category, k string) error {
+	if af.readOnly {
+		return af.keyError("delete", category, k, ErrReadOnly)
+	}
+	oldVal, existed := af.Values[category][k]
+	if !existed {
+		return nil
+	}
+	delete(af.Values[category], k)
+	if err := af.save(); err != nil {
+		af.Values[category][k] = oldVal
+		return err
+	}
+	return nil
+}
+
+// DeleteCategory removes a category and all of its keys from af, if unable
+// to save, revert (and return the error)
+func (af *AddonConfig) DeleteCategory(category string) error {
+	if af.readOnly {
+		return af.keyError("delete", category, "", ErrReadOnly)
+	}
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	oldVals, existed := af.Values[category]
+	if !existed {
+		return nil
+	}
+	delete(af.Values, category)
+	if err := af.save(); err != nil {
+		af.Values[category] = oldVals
+		return err
+	}
+	return nil
+}
+
+// RenameCategory moves every key in category from to category to, if
+// unable to save, revert (and return the error)
+func (af *AddonConfig) RenameCategory(from, to string) error {
+	if af.readOnly {
+		return af.keyError("rename", from, "", ErrReadOnly)
+	}
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	vals, ok := af.Values[from]
+	if !ok {
+		return af.keyError("rename", from, "", ErrNoKey)
+	}
+	if _, ok = af.Values[to]; ok {
+		return af.keyError("rename", to, "", ErrCategoryExists)
+	}
+	delete(af.Values, from)
+	af.Values[to] = vals
+	if err := af.save(); err != nil {
+		delete(af.Values, to)
+		af.Values[from] = vals
+		return err
+	}
+	return nil
+}
+
+// ListCategories returns a sorted list of the categories in af
+func (af *AddonConfig) ListCategories() []string {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	var ret []string
+	for category := range af.Values {
+		ret = append(ret, category)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// ListKeys returns a sorted list of the keys in a category of af
+func (af *AddonConfig) ListKeys(category string) []string {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	var ret []string
+	for k := range af.Values[category] {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// IsReadOnly returns whether af was opened read-only
+func (af *AddonConfig) IsReadOnly() bool {
+	return af.readOnly
+}
+
+// keyError wraps err with the operation and "<category>.<key>" that caused
+// it
+func (af *AddonConfig) keyError(op, category, k string, err error) error {
+	if k != "" {
+		category += "." + k
+	}
+	return &ConfigError{Op: op, Path: af.GetFullPath(), Key: category, Err: err}
 }
 
 // GetFullPath returns the full path & filename to the config file
 func (af *AddonConfig) GetFullPath() string {
-	return af.Path + "/" + af.Name + ".toml"
+	ext := af.ext
+	if ext == "" {
+		ext = DefaultNaming.AddonExt
+	}
+	return filepath.Join(af.Path, af.Name+ext)
 }
-
-/** END of ConfigFile Interface Implementation **/
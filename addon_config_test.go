@@ -0,0 +1,134 @@
+package userConfig_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+// withAddon returns a config listing the addon "extra", written by hand as
+// data, and the FaultyFS both are read and written with
+func withAddon(t *testing.T, data string) (*userConfig.AddonConfig, *userconfigtest.FaultyFS) {
+	t.Helper()
+	fs := &userconfigtest.FaultyFS{FileSystem: userConfig.OSFileSystem{}}
+	c := userconfigtest.FromTOML(t, "app", "additional_config = [\"extra\"]\n[general]\n", userConfig.WithFileSystem(fs))
+	if err := os.WriteFile(filepath.Join(c.GetConfigPath(), "extra.toml"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	af, err := c.GetAddonConfig("extra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return af, fs
+}
+
+func TestAddonNativeValues(t *testing.T) {
+	af, _ := withAddon(t, `[server]
+port = 8080
+ratio = 0.5
+debug = true
+timeout = "1m30s"
+started = 2020-01-02T03:04:05Z
+hosts = ["a", "b"]
+`)
+	if v, err := af.GetInt("server", "port"); err != nil || v != 8080 {
+		t.Errorf("port = %d, %v, want 8080", v, err)
+	}
+	if v, err := af.GetFloat("server", "ratio"); err != nil || v != 0.5 {
+		t.Errorf("ratio = %g, %v, want 0.5", v, err)
+	}
+	if v, err := af.GetBool("server", "debug"); err != nil || !v {
+		t.Errorf("debug = %t, %v, want true", v, err)
+	}
+	if v, err := af.GetDuration("server", "timeout"); err != nil || v != 90*time.Second {
+		t.Errorf("timeout = %s, %v, want 1m30s", v, err)
+	}
+	want := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if v, err := af.GetDateTime("server", "started"); err != nil || !v.Equal(want) {
+		t.Errorf("started = %s, %v, want %s", v, err, want)
+	}
+	if v, err := af.GetArray("server", "hosts"); err != nil || !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("hosts = %v, %v, want [a b]", v, err)
+	}
+
+	// Written back as strings, and read the same way
+	if err := af.Set("server", "name", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := af.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := af.GetInt("server", "port"); err != nil || v != 8080 {
+		t.Errorf("port = %d, %v after saving, want 8080", v, err)
+	}
+}
+
+func TestAddonCategories(t *testing.T) {
+	af, fs := withAddon(t, "[a]\nk = \"1\"\n[b]\nk = \"2\"\n")
+
+	if err := af.RenameCategory("a", "b"); !errors.Is(err, userConfig.ErrCategoryExists) {
+		t.Errorf("renaming onto a category returned %v, want ErrCategoryExists", err)
+	}
+	if err := af.RenameCategory("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if cats := af.ListCategories(); !reflect.DeepEqual(cats, []string{"b", "c"}) {
+		t.Errorf("categories after renaming = %v, want [b c]", cats)
+	}
+	if err := af.DeleteCategory("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := af.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cats := af.ListCategories(); !reflect.DeepEqual(cats, []string{"c"}) {
+		t.Errorf("saved categories = %v, want [c]", cats)
+	}
+	if v := af.Get("c", "k"); v != "1" {
+		t.Errorf("c.k = %q, want 1", v)
+	}
+
+	// Nothing changes when the file can't be saved
+	fs.FailWrites(nil)
+	if err := af.Set("c", "k", "2"); !errors.Is(err, userconfigtest.ErrInjected) {
+		t.Errorf("Set with failing writes returned %v, want ErrInjected", err)
+	}
+	if err := af.Set("new", "k", "2"); !errors.Is(err, userconfigtest.ErrInjected) {
+		t.Errorf("Set with failing writes returned %v, want ErrInjected", err)
+	}
+	if err := af.DeleteKey("c", "k"); !errors.Is(err, userconfigtest.ErrInjected) {
+		t.Errorf("DeleteKey with failing writes returned %v, want ErrInjected", err)
+	}
+	if err := af.RenameCategory("c", "d"); !errors.Is(err, userconfigtest.ErrInjected) {
+		t.Errorf("RenameCategory with failing writes returned %v, want ErrInjected", err)
+	}
+	if err := af.DeleteCategory("c"); !errors.Is(err, userconfigtest.ErrInjected) {
+		t.Errorf("DeleteCategory with failing writes returned %v, want ErrInjected", err)
+	}
+	if cats := af.ListCategories(); !reflect.DeepEqual(cats, []string{"c"}) {
+		t.Errorf("categories after failed changes = %v, want [c]", cats)
+	}
+	if v := af.Get("c", "k"); v != "1" {
+		t.Errorf("c.k = %q after failed changes, want 1", v)
+	}
+}
+
+func TestAddonNameOutsideConfigDir(t *testing.T) {
+	c := userconfigtest.FromTOML(t, "app", "additional_config = [\"../../evil\"]\n[general]\n")
+	if _, err := c.GetAddonConfig("../../evil"); !errors.Is(err, userConfig.ErrInvalidName) {
+		t.Errorf("GetAddonConfig of a name outside the config dir returned %v, want ErrInvalidName", err)
+	}
+	if _, err := userConfig.NewAddonConfig("../../evil", c.GetConfigPath()); !errors.Is(err, userConfig.ErrInvalidName) {
+		t.Errorf("NewAddonConfig of a name outside the config dir returned %v, want ErrInvalidName", err)
+	}
+	evil := filepath.Join(c.GetConfigPath(), "../../evil.toml")
+	if _, err := os.Stat(evil); err == nil {
+		t.Errorf("an addon was written to %s, outside the config dir", evil)
+	}
+}
@@ -0,0 +1,104 @@
+package userConfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// AppDir is a directory owned by the application outside of its config
+// directory, like its XDG data or cache directory
+type AppDir struct {
+	Path string
+	// Mode is used when creating the directory, DefaultDirMode if unset
+	Mode os.FileMode
+}
+
+// NewAppDir generates an AppDir struct for path
+// The directory isn't created until something is written to it
+func NewAppDir(path string) *AppDir {
+	return &AppDir{Path: path}
+}
+
+// Verify makes sure the directory exists, creating it if it doesn't
+func (d *AppDir) Verify() error {
+	return verifyOrCreateDirectory(d.Path, d.Mode)
+}
+
+// GetFullPath returns the full path to the file name inside the directory
+func (d *AppDir) GetFullPath(name string) string {
+	return filepath.Join(d.Path, name)
+}
+
+// ReadFile reads the file name from the directory
+func (d *AppDir) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(d.GetFullPath(name))
+}
+
+// WriteFile writes data to the file name in the directory, creating the
+// directory first if needed
+func (d *AppDir) WriteFile(name string, data []byte) error {
+	if err := d.Verify(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(d.GetFullPath(name), data, 0644)
+}
+
+// RemoveFile removes the file name from the directory
+func (d *AppDir) RemoveFile(name string) error {
+	return os.Remove(d.GetFullPath(name))
+}
+
+// Evict removes files from the directory that are older than maxAge, then
+// removes the oldest remaining files until the directory holds no more than
+// maxSize bytes. A zero maxAge or maxSize disables that limit.
+func (d *AppDir) Evict(maxSize int64, maxAge time.Duration) error {
+	type entry struct {
+		path string
+		info os.FileInfo
+	}
+	var files []entry
+	err := filepath.Walk(d.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, entry{path: path, info: info})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Oldest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].info.ModTime().Before(files[j].info.ModTime())
+	})
+
+	var total int64
+	var keep []entry
+	now := time.Now()
+	for _, f := range files {
+		if maxAge > 0 && now.Sub(f.info.ModTime()) > maxAge {
+			if err = os.Remove(f.path); err != nil {
+				return err
+			}
+			continue
+		}
+		total += f.info.Size()
+		keep = append(keep, f)
+	}
+	for i := 0; maxSize > 0 && total > maxSize && i < len(keep); i++ {
+		if err = os.Remove(keep[i].path); err != nil {
+			return err
+		}
+		total -= keep[i].info.Size()
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package userConfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/casimir/xdg-go"
+)
+
+// maxAppDepth is how many levels deep ListConfigs looks for configs, enough
+// for names like "vendor/suite/tool"
+const maxAppDepth = 4
+
+// ValidateAppName returns an error matching ErrInvalidName if name can't be
+// used as an app name. Vendor-qualified names like "acme/tool" are fine and
+// give the app nested XDG directories, but every part of the name has to be
+// a valid file name, so absolute paths, "..", backslashes and empty parts
+// are all rejected.
+func ValidateAppName(name string) error {
+	if name == "" || strings.HasPrefix(name, "/") {
+		return fmt.Errorf("%q: %w", name, ErrInvalidName)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if err := ValidateFileName(part); err != nil {
+			return fmt.Errorf("%q: %w", name, ErrInvalidName)
+		}
+	}
+	return nil
+}
+
+// appFileName returns the name of an app's general file, the last part of
+// a vendor-qualified name
+func appFileName(name string) string {
+	return path.Base(name)
+}
+
+// ListConfigs returns the name of every app under XDG_CONFIG_HOME that has
+// a config made with this library, sorted
+func ListConfigs() ([]string, error) {
+	return listConfigsIn(xdg.App{}.ConfigPath(""))
+}
+
+// listConfigsIn returns the name of every app under root that has a config
+// made with this library: a directory with a <dir>.conf in it that has one
+// of the tables or keys the library writes
+func listConfigsIn(root string) ([]string, error) {
+	var ret []string
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			// Skip what we can't read
+			return nil
+		}
+		if !fi.IsDir() || p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		if strings.Count(name, "/") >= maxAppDepth {
+			return filepath.SkipDir
+		}
+		if ValidateAppName(name) == nil && isLibraryConfig(filepath.Join(p, fi.Name()+DefaultNaming.GeneralExt)) {
+			ret = append(ret, name)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return ret, nil
+	} else if err != nil {
+		return ret, &ConfigError{Op: "list", Path: root, Err: err}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// isLibraryConfig returns whether the file at cfgPath looks like a general
+// config file written by this library
+func isLibraryConfig(cfgPath string) bool {
+	data, err := ioutil.ReadFile(cfgPath)
+	if err != nil {
+		return false
+	}
+	var file generalFile
+	md, err := decodeTOML(string(data), &file)
+	if err != nil {
+		return false
+	}
+	return md.IsDefined("general") || md.IsDefined("additional_config") || md.IsDefined("raw_files")
+}
+
+// AppInfo summarizes an app's config, as found by ScanConfigs
+type AppInfo struct {
+	Name string
+	// Path is the config directory
+	Path string
+	// System is whether the config is in XDG_CONFIG_DIRS rather than
+	// XDG_CONFIG_HOME
+	System   bool
+	Keys     int
+	Addons   []string
+	RawFiles []string
+	// Size is the total size of the general, addon and raw files, and
+	// ModTime the last time one of them was modified
+	Size    int64
+	ModTime time.Time
+}
+
+// ScanConfigs returns an AppInfo for every config made with this library
+// under XDG_CONFIG_HOME and, if system is set, XDG_CONFIG_DIRS
+// Configs that can't be read are skipped.
+func ScanConfigs(system bool) ([]AppInfo, error) {
+	roots := []string{xdg.App{}.ConfigPath("")}
+	if system {
+		roots = append(roots, xdg.App{}.SystemConfigPaths("")...)
+	}
+	var ret []AppInfo
+	for i, root := range roots {
+		names, err := listConfigsIn(root)
+		if err != nil {
+			return ret, err
+		}
+		for _, name := range names {
+			if info, err := readAppInfo(root, name); err == nil {
+				info.System = i > 0
+				ret = append(ret, *info)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// readAppInfo builds the AppInfo for the app called name under root
+func readAppInfo(root, name string) (*AppInfo, error) {
+	dir := filepath.Join(root, filepath.FromSlash(name))
+	gf, err := newGeneralConfig(appFileName(name), dir, DefaultNaming.GeneralExt, true, OSFileSystem{})
+	if err != nil {
+		return nil, err
+	}
+	info := &AppInfo{
+		Name:     name,
+		Path:     dir,
+		Keys:     len(gf.GetKeyList()),
+		Addons:   gf.ConfigFiles,
+		RawFiles: gf.RawFiles,
+	}
+	files := []string{gf.GetFullPath()}
+	for _, addon := range gf.ConfigFiles {
+		files = append(files, filepath.Join(dir, addon+DefaultNaming.AddonExt))
+	}
+	for _, raw := range gf.RawFiles {
+		files = append(files, filepath.Join(dir, raw))
+	}
+	for _, f := range files {
+		if fi, err := os.Stat(f); err == nil {
+			info.Size += fi.Size()
+			if fi.ModTime().After(info.ModTime) {
+				info.ModTime = fi.ModTime()
+			}
+		}
+	}
+	return info, nil
+}
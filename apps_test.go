@@ -0,0 +1,76 @@
+package userConfig_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+func TestValidateAppName(t *testing.T) {
+	for name, ok := range map[string]bool{
+		"tool":       true,
+		"acme/tool":  true,
+		"../x":       false,
+		"/abs":       false,
+		"a//b":       false,
+		`a\b`:        false,
+		" a":         false,
+		"":           false,
+		"acme/tool/": false,
+	} {
+		err := userConfig.ValidateAppName(name)
+		if ok && err != nil {
+			t.Errorf("ValidateAppName(%q) = %v, want nil", name, err)
+		} else if !ok && !errors.Is(err, userConfig.ErrInvalidName) {
+			t.Errorf("ValidateAppName(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestNewConfigInvalidName(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg", "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "xdg", "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(root, "xdg", "cache"))
+
+	if _, err := userConfig.NewConfig("../x"); !errors.Is(err, userConfig.ErrInvalidName) {
+		t.Errorf(`NewConfig("../x") returned %v, want ErrInvalidName`, err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Errorf("NewConfig with an invalid name created %v", entries)
+	}
+}
+
+func TestListConfigs(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeFiles(t, root, map[string]string{
+		"tool/tool.conf":           "[general]\na = \"1\"\n",
+		"acme/suite/suite.conf":    "additional_config = []\n",
+		"acme/acme.conf":           "[general]\n",
+		"other/other.conf":         "not = \"ours\"\n",
+		"broken/broken.conf":       "[general\n",
+		"named/wrong.conf":         "[general]\n",
+		"a/b/c/d/e/e.conf":         "[general]\n",
+		"tool/nested/nested.conf":  "[general]\n",
+		"tool/nested/ignored.toml": "",
+	})
+
+	names, err := userConfig.ListConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"acme", "acme/suite", "tool", "tool/nested"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ListConfigs() = %v, want %v", names, want)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "missing"))
+	if names, err = userConfig.ListConfigs(); err != nil || len(names) != 0 {
+		t.Errorf("ListConfigs() of a missing directory = %v, %v, want nothing", names, err)
+	}
+}
@@ -0,0 +1,219 @@
+package userConfig
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BytesEncoding is how SetBytes stores binary values in a config file
+type BytesEncoding string
+
+const (
+	// EncodingBase64 stores bytes as "base64:<standard base64>"
+	EncodingBase64 BytesEncoding = "base64"
+	// EncodingHex stores bytes as "hex:<hex digits>"
+	EncodingHex BytesEncoding = "hex"
+)
+
+// DefaultBlobLimit is the size in bytes above which SetBytes writes the
+// value to a raw file instead of the config file
+const DefaultBlobLimit = 64 * 1024
+
+// blobPrefix marks a value that was written to a raw file
+const blobPrefix = "file:"
+
+// Anything else in a key is replaced when naming its raw file
+var blobNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// encodeBytes encodes v with its encoding marker
+func encodeBytes(v []byte, enc BytesEncoding) string {
+	if enc == EncodingHex {
+		return string(EncodingHex) + ":" + hex.EncodeToString(v)
+	}
+	return string(EncodingBase64) + ":" + base64.StdEncoding.EncodeToString(v)
+}
+
+// decodeBytes decodes a value written by encodeBytes
+// Values without a marker were written before values were encoded, so
+// they're returned as they are.
+func decodeBytes(s string) []byte {
+	if strings.HasPrefix(s, string(EncodingBase64)+":") {
+		if b, err := base64.StdEncoding.DecodeString(s[len(EncodingBase64)+1:]); err == nil {
+			return b
+		}
+	}
+	if strings.HasPrefix(s, string(EncodingHex)+":") {
+		if b, err := hex.DecodeString(s[len(EncodingHex)+1:]); err == nil {
+			return b
+		}
+	}
+	return []byte(s)
+}
+
+// SetBytes sets a binary value in the config file, encoded so that any
+// bytes survive the round trip. Values bigger than the blob limit are
+// written to a raw file that the config file points to.
+func (gf *GeneralConfig) SetBytes(k string, v []byte) error {
+	limit := gf.blobLimit
+	if limit == 0 {
+		limit = DefaultBlobLimit
+	}
+	if limit < 0 || len(v) <= limit {
+		return gf.Set(k, encodeBytes(v, gf.bytesEncoding))
+	}
+
+	if gf.readOnly {
+		return gf.keyError("set", k, ErrReadOnly)
+	}
+	// The value only points to the new file once the config file is saved,
+	// so a failed save leaves the old value, and its file, as they were
+	name := blobName(k, v)
+	blobPath := filepath.Join(gf.Path, name)
+	gf.mu.Lock()
+	fresh := false
+	if _, err := gf.fileSystem().ReadFile(blobPath); err != nil {
+		// Names come from the contents, so one that's there already has them
+		fresh = os.IsNotExist(err)
+		if err = gf.fileSystem().WriteFile(blobPath, v, 0644); err != nil {
+			gf.mu.Unlock()
+			return gf.keyError("set", k, err)
+		}
+	}
+	ch, err := gf.setBlob(k, name)
+	if err != nil && fresh {
+		gf.fileSystem().Remove(blobPath)
+	}
+	gf.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	gf.changed(ch)
+	return nil
+}
+
+// setBlob points k at the raw file name, listing it in raw_files, gf.mu
+// has to be held
+func (gf *GeneralConfig) setBlob(k, name string) (Change, error) {
+	oldRaw := gf.RawFiles
+	if !gf.listsBlob(name) {
+		gf.RawFiles = append(gf.RawFiles, name)
+	}
+	ch, err := gf.set(k, blobPrefix+name)
+	if err != nil {
+		gf.RawFiles = oldRaw
+	}
+	return ch, err
+}
+
+// restore sets k back to a value it had before, which may point to a raw
+// file that has been taken off the raw_files list since
+func (gf *GeneralConfig) restore(k, v string) error {
+	if !strings.HasPrefix(v, blobPrefix) {
+		return gf.Set(k, v)
+	}
+	name := filepath.Base(v[len(blobPrefix):])
+	gf.mu.Lock()
+	ch, err := gf.setBlob(k, name)
+	gf.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	gf.changed(ch)
+	return nil
+}
+
+// GetBytes gets a key/value pair from gf and returns it as a byte slice,
+// reading it from its raw file if it was too big for the config file
+// A raw file that can't be read returns nil.
+func (gf *GeneralConfig) GetBytes(k string) []byte {
+	v := gf.Get(k)
+	if strings.HasPrefix(v, blobPrefix) {
+		b, err := gf.fileSystem().ReadFile(filepath.Join(gf.Path, filepath.Base(v[len(blobPrefix):])))
+		if err != nil {
+			return nil
+		}
+		return b
+	}
+	return decodeBytes(v)
+}
+
+// blobName returns the name of the raw file for k set to v
+// The hash of the key and value keeps keys that only differ in the replaced
+// characters, like "a b" and "a_b", from sharing a file, and gives every
+// new value a file of its own.
+func blobName(k string, v []byte) string {
+	sum := sha1.New()
+	sum.Write([]byte(k + "\x00"))
+	sum.Write(v)
+	return blobNameRegexp.ReplaceAllString(k, "_") + "-" + hex.EncodeToString(sum.Sum(nil))[:16] + ".bin"
+}
+
+// releaseBlob takes the raw file that old points to off the raw_files list
+// when a value changes from old to new, gf.mu has to be held. It returns
+// the file the change releases, and the list to put back if the change
+// isn't saved.
+func (gf *GeneralConfig) releaseBlob(old, new string) (string, []string) {
+	oldRaw := gf.RawFiles
+	name := blobOf(old)
+	if name == "" || old == new {
+		return "", oldRaw
+	}
+	var raw []string
+	for _, f := range oldRaw {
+		if f != name {
+			raw = append(raw, f)
+		}
+	}
+	if len(raw) == len(oldRaw) {
+		// Not one of ours
+		return "", oldRaw
+	}
+	if raw == nil {
+		raw = []string{}
+	}
+	gf.RawFiles = raw
+	return name, oldRaw
+}
+
+// listsBlob returns whether name is on the raw_files list, gf.mu has to
+// be held
+func (gf *GeneralConfig) listsBlob(name string) bool {
+	for _, f := range gf.RawFiles {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// blobOf returns the name of the raw file v points to, if it does
+func blobOf(v string) string {
+	if !strings.HasPrefix(v, blobPrefix) {
+		return ""
+	}
+	return filepath.Base(v[len(blobPrefix):])
+}
+
+// removeBlobs removes the raw files in names that nothing uses any more
+// A released file is kept as long as the journal has a change to or from
+// it, so undoing that change can point back to it. Failing to remove one
+// is only left-over data.
+func (gf *GeneralConfig) removeBlobs(names []string, journal []Change) {
+	inJournal := make(map[string]bool)
+	for _, ch := range journal {
+		inJournal[blobOf(ch.Old)] = true
+		inJournal[blobOf(ch.New)] = true
+	}
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	for _, name := range names {
+		if name != "" && !inJournal[name] && !gf.listsBlob(name) {
+			gf.fileSystem().Remove(filepath.Join(gf.Path, name))
+		}
+	}
+}
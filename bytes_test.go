@@ -0,0 +1,119 @@
+package userConfig_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestBlobNames(t *testing.T) {
+	c := userconfigtest.New(t, "app", userConfig.WithBlobLimit(4))
+	if err := c.SetBytes("a b", []byte("first value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetBytes("a_b", []byte("second value")); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetBytes("a b"); !bytes.Equal(got, []byte("first value")) {
+		t.Errorf(`"a b" = %q, want "first value"`, got)
+	}
+	if got := c.GetBytes("a_b"); !bytes.Equal(got, []byte("second value")) {
+		t.Errorf(`"a_b" = %q, want "second value"`, got)
+	}
+}
+
+func TestBlobCleanup(t *testing.T) {
+	// Without a journal nothing can point back to a released raw file
+	c := userconfigtest.New(t, "app", userConfig.WithBlobLimit(4), userConfig.WithHistoryLimit(-1))
+	blobs := func() []string {
+		matches, _ := filepath.Glob(filepath.Join(c.GetConfigPath(), "*.bin"))
+		return matches
+	}
+	if err := c.SetBytes("big", []byte("too big to inline")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetBytes("other", []byte("also too big")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(blobs()); n != 2 {
+		t.Fatalf("%d raw files after two large values, want 2", n)
+	}
+
+	// Replaced by a value small enough for the config file
+	if err := c.SetBytes("big", []byte("tiny")); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetBytes("big"); !bytes.Equal(got, []byte("tiny")) {
+		t.Errorf("big = %q, want tiny", got)
+	}
+	if n := len(blobs()); n != 1 {
+		t.Errorf("%d raw files after replacing one, want 1", n)
+	}
+
+	if err := c.DeleteKey("other"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(blobs()); n != 0 {
+		t.Errorf("%d raw files after deleting the key, want 0", n)
+	}
+	if saved := userconfigtest.SavedContent(t, c); strings.Contains(saved, ".bin") {
+		t.Errorf("raw_files still lists a removed file:\n%s", saved)
+	}
+}
+
+// confFailFS fails writes of config files while fail is set, leaving raw
+// files alone
+type confFailFS struct {
+	userConfig.OSFileSystem
+	fail bool
+}
+
+var errConfWrite = errors.New("config write failed")
+
+func (fs *confFailFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if fs.fail && strings.HasSuffix(name, ".conf") {
+		return errConfWrite
+	}
+	return fs.OSFileSystem.WriteFile(name, data, perm)
+}
+
+func TestBlobConfigWriteFailure(t *testing.T) {
+	fs := &confFailFS{}
+	c := userconfigtest.New(t, "app", userConfig.WithFileSystem(fs), userConfig.WithBlobLimit(4))
+	blobs := func() []string {
+		matches, _ := filepath.Glob(filepath.Join(c.GetConfigPath(), "*.bin"))
+		return matches
+	}
+	old := []byte("too big to inline")
+	if err := c.SetBytes("big", old); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.fail = true
+	if err := c.SetBytes("big", []byte("a new large value")); !errors.Is(err, errConfWrite) {
+		t.Fatalf("SetBytes with a failing config write returned %v, want errConfWrite", err)
+	}
+	if got := c.GetBytes("big"); !bytes.Equal(got, old) {
+		t.Errorf("big = %q after a failed SetBytes, want %q", got, old)
+	}
+	if err := c.SetBytes("new", []byte("another large value")); !errors.Is(err, errConfWrite) {
+		t.Fatalf("SetBytes with a failing config write returned %v, want errConfWrite", err)
+	}
+	if n := len(blobs()); n != 1 {
+		t.Errorf("%d raw files after failed SetBytes calls, want 1", n)
+	}
+
+	fs.fail = false
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetBytes("big"); !bytes.Equal(got, old) {
+		t.Errorf("big = %q after reloading, want %q", got, old)
+	}
+}
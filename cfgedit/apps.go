@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+// listApps prints every app that has a config made with userConfig
+func listApps(args []string) error {
+	system := false
+	for _, a := range args {
+		if a != "--system" {
+			return errors.New("Usage: apps [--system]")
+		}
+		system = true
+	}
+	apps, err := userConfig.ScanConfigs(system)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tKEYS\tADDONS\tRAW FILES\tSIZE\tMODIFIED\tLOCATION")
+	for _, a := range apps {
+		modified := ""
+		if !a.ModTime.IsZero() {
+			modified = a.ModTime.Local().Format("2006-01-02 15:04")
+		}
+		location := a.Path
+		if a.System {
+			location += " (system)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\t%s\n", a.Name, a.Keys, strings.Join(a.Addons, ","), len(a.RawFiles), a.Size, modified, location)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+func diff(cfg *userConfig.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: diff <other-file>")
+	}
+	other := args[0]
+	if filepath.Ext(other) != ".conf" {
+		return errors.New("Can only diff against a .conf file: " + other)
+	}
+	otherCfg, err := userConfig.NewReadOnlyGeneralConfig(
+		strings.TrimSuffix(filepath.Base(other), ".conf"), filepath.Dir(other))
+	if err != nil {
+		return err
+	}
+
+	d := userConfig.Diff(cfg, otherCfg)
+	if d.Empty() {
+		fmt.Println("No differences")
+		return nil
+	}
+	color := useColor()
+	for _, k := range d.Added {
+		printColored(color, colorGreen, fmt.Sprintf("+ %s = %q", k.Key, k.New))
+	}
+	for _, k := range d.Removed {
+		printColored(color, colorRed, fmt.Sprintf("- %s = %q", k.Key, k.Old))
+	}
+	for _, k := range d.Changed {
+		printColored(color, colorYellow, fmt.Sprintf("~ %s: %q -> %q", k.Key, k.Old, k.New))
+	}
+	return nil
+}
+
+// useColor only colors output going to a terminal
+func useColor() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func printColored(color bool, code, line string) {
+	if color {
+		line = code + line + colorReset
+	}
+	fmt.Println(line)
+}
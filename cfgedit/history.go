@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+// timeLayouts are the formats accepted by restore --at
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func printHistory(cfg *userConfig.Config, args []string) error {
+	var key string
+	if len(args) > 0 {
+		key = args[0]
+	}
+	changes, err := cfg.History(key)
+	if err != nil {
+		return err
+	}
+	for _, ch := range changes {
+		desc := fmt.Sprintf("%q -> %q", ch.Old, ch.New)
+		switch {
+		case ch.Deleted:
+			desc = fmt.Sprintf("deleted (was %q)", ch.Old)
+		case !ch.Existed:
+			desc = fmt.Sprintf("added %q", ch.New)
+		}
+		fmt.Printf("%s  %s  %s: %s\n", ch.Time.Format(time.RFC3339), ch.Process, ch.Key, desc)
+	}
+	return nil
+}
+
+func undo(cfg *userConfig.Config, args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return errors.New("undo expects a positive number of changes")
+		}
+	}
+	return cfg.Undo(n)
+}
+
+func restore(cfg *userConfig.Config, args []string) error {
+	if len(args) != 2 || args[0] != "--at" {
+		return errors.New("Usage: restore --at <time>")
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, args[1], time.Local); err == nil {
+			return cfg.RestoreAt(t)
+		}
+	}
+	return errors.New("Couldn't parse time: " + args[1])
+}
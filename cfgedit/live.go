@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+// live runs one of the live-* operations against the control socket of a
+// running process using the config
+func live(whichConfig, op string, args []string) error {
+	cl, err := userConfig.DialSocket(whichConfig)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	switch op {
+	case "live-get":
+		if len(args) != 1 {
+			return errors.New("Usage: live-get <key>")
+		}
+		v, err := cl.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s = %q\n", args[0], v)
+	case "live-set":
+		if len(args) < 2 {
+			return errors.New("Usage: live-set <key> <value>")
+		}
+		if err = cl.Set(args[0], strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+		fmt.Println("Set " + args[0])
+	case "live-delete":
+		if len(args) != 1 {
+			return errors.New("Usage: live-delete <key>")
+		}
+		if err = cl.DeleteKey(args[0]); err != nil {
+			return err
+		}
+		fmt.Println("Deleted " + args[0])
+	case "live-watch":
+		pattern := "*"
+		if len(args) > 0 {
+			pattern = args[0]
+		}
+		if err = cl.Subscribe(pattern); err != nil {
+			return err
+		}
+		for ch := range cl.Changes() {
+			fmt.Printf("%s: %q -> %q\n", ch.Key, ch.Old, ch.New)
+		}
+	default:
+		printHelp()
+	}
+	return nil
+}
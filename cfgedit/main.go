@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
 
 	userConfig "github.com/br0xen/user-config"
 )
@@ -14,24 +18,145 @@ func main() {
 		printHelp()
 		os.Exit(1)
 	}
-	whichConfig := os.Args[1]
-	cfg, err := userConfig.NewConfig(whichConfig)
-	if err != nil {
-		fmt.Println("Couldn't find config directory: " + whichConfig)
-		os.Exit(1)
+	if os.Args[1] == "apps" && (len(os.Args) == 2 || strings.HasPrefix(os.Args[2], "--")) {
+		// Not a config, look for all of them
+		if err := listApps(os.Args[2:]); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+		return
 	}
+	whichConfig := os.Args[1]
 	op := "list"
+	var args []string
 	if len(os.Args) >= 3 {
 		op = os.Args[2]
+		args = os.Args[3:]
+	}
+	if strings.HasPrefix(op, "live-") {
+		// Talk to the running process instead of the files
+		if err := live(whichConfig, op, args); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+		return
+	}
+	opts := []userConfig.Option{userConfig.WithInterpolation()}
+	switch op {
+	case "list", "get", "history", "snapshots", "diff":
+		// Just looking, don't create anything
+		opts = append(opts, userConfig.ReadOnly)
+	}
+	cfg, err := userConfig.NewConfig(whichConfig, opts...)
+	if err != nil {
+		printError(err)
+		os.Exit(1)
 	}
 	switch op {
 	case "list":
-		fmt.Println(cfg.GetKeyList())
+		if len(args) > 0 && args[0] == "--long" {
+			err = listLong(cfg)
+		} else {
+			fmt.Println(cfg.GetKeyList())
+		}
+	case "get":
+		err = get(cfg, args)
+	case "history":
+		err = printHistory(cfg, args)
+	case "undo":
+		err = undo(cfg, args)
+	case "restore":
+		err = restore(cfg, args)
+	case "diff":
+		err = diff(cfg, args)
+	case "snapshot":
+		err = snapshot(cfg, args)
+	case "snapshots":
+		err = listSnapshots(cfg)
+	case "restore-snapshot":
+		err = restoreSnapshot(cfg, args)
+	default:
+		printHelp()
+		os.Exit(1)
+	}
+	if err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+}
+
+func listLong(cfg *userConfig.Config) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tSOURCE\tMODIFIED\tDESCRIPTION")
+	for _, k := range cfg.GetKeyList() {
+		info, err := cfg.Describe(k)
+		if err != nil {
+			return err
+		}
+		modified := ""
+		if !info.ModTime.IsZero() {
+			modified = info.ModTime.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", k, info.Type, filepath.Base(info.Source), modified, info.Description)
+	}
+	return w.Flush()
+}
+
+func get(cfg *userConfig.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: get <key>")
+	}
+	raw := cfg.GetRaw(args[0])
+	fmt.Printf("%s = %q\n", args[0], raw)
+	if src := cfg.Source(args[0]); src != "" {
+		fmt.Println("  defined in " + src)
+	}
+	expanded, err := cfg.GetExpanded(args[0])
+	if err != nil {
+		return err
+	}
+	if expanded != raw {
+		fmt.Printf("  expands to %q\n", expanded)
+	}
+	return nil
+}
+
+// printError prints err with as much detail as the library gave us
+func printError(err error) {
+	var pe *userConfig.ParseError
+	var ce *userConfig.ConfigError
+	switch {
+	case errors.As(err, &pe):
+		fmt.Println("Invalid config file " + pe.Error())
+	case errors.Is(err, userConfig.ErrNotExist):
+		fmt.Println("Couldn't find config: " + err.Error())
+	case errors.Is(err, userConfig.ErrNotDirectory):
+		fmt.Println("Not a directory: " + err.Error())
+	case errors.Is(err, userConfig.ErrPermission):
+		fmt.Println("Permission denied: " + err.Error())
+	case errors.As(err, &ce):
+		fmt.Println("Couldn't " + ce.Error())
+	default:
+		fmt.Println(err.Error())
 	}
 }
 
 func printHelp() {
 	fmt.Println("Usage: " + AppName + " <which config> <operation>")
+	fmt.Println("       " + AppName + " apps [--system]   list every app with a config")
 	fmt.Println("  <which-config> is ~/.config/<which-config>")
-	fmt.Println("  <operation> can just be 'list' right now")
+	fmt.Println("  <operation> can be:")
+	fmt.Println("    list [--long]            list the keys in the config")
+	fmt.Println("    get <key>                show a value, and what it expands to")
+	fmt.Println("    history [key]            show the journal of changes")
+	fmt.Println("    undo [n]                 revert the last n changes (default 1)")
+	fmt.Println("    restore --at <time>      revert every change made after <time>")
+	fmt.Println("    diff <other-file>        compare the config to another .conf file")
+	fmt.Println("    snapshot [label]         archive the config directory")
+	fmt.Println("    snapshots                list the snapshots")
+	fmt.Println("    restore-snapshot <id>    put the files from a snapshot back")
+	fmt.Println("    live-get <key>           get a value from a running process")
+	fmt.Println("    live-set <key> <value>   change a value in a running process")
+	fmt.Println("    live-delete <key>        remove a key in a running process")
+	fmt.Println("    live-watch [pattern]     print changes made in a running process")
 }
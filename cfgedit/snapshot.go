@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+func snapshot(cfg *userConfig.Config, args []string) error {
+	info, err := cfg.Snapshot(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	fmt.Println("Created snapshot " + info.ID)
+	return nil
+}
+
+func listSnapshots(cfg *userConfig.Config) error {
+	snaps, err := cfg.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	for _, s := range snaps {
+		fmt.Printf("%s  %s  %d bytes  %s\n", s.ID, s.Time.Local().Format(time.RFC3339), s.Size, s.Label)
+	}
+	return nil
+}
+
+func restoreSnapshot(cfg *userConfig.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: restore-snapshot <id>")
+	}
+	return cfg.RestoreSnapshot(args[0])
+}
@@ -3,145 +3,480 @@ package userConfig
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/casimir/xdg-go"
 )
 
+// DefaultDirMode is the mode used for directories created by the config
+const DefaultDirMode os.FileMode = 0755
+
 // Config is a stuct for managing the config
 type Config struct {
 	name          string
 	generalConfig *GeneralConfig
+	dataDir       *AppDir
+	cacheDir      *AppDir
+
+	dir         string
+	fileName    string
+	searchPaths []string
+	dirMode     os.FileMode
+	readOnly    bool
+
+	historyLimit int
+	replaying    bool
+
+	interpolate bool
+	addons      map[string]*AddonConfig
+	schema      map[string]KeySchema
+
+	bytesEncoding BytesEncoding
+	blobLimit     int
+
+	dateTimeLayouts []string
+	location        *time.Location
+
+	fs        FileSystem
+	dataPath  string
+	cachePath string
+
+	shared []sharedImport
+
+	naming  NamingPolicy
+	migrate bool
+
+	// files are the ConfigFiles added with RegisterFile
+	files map[string]ConfigFile
+
+	// writeMu is held by every change made through c, and by Reload from
+	// loading the files until the new values are swapped in, so a change
+	// is never made to a general config that's being replaced
+	// It's taken before mu.
+	writeMu sync.Mutex
+	// mu guards the generalConfig pointer, addons, files, watchers and
+	// validators
+	mu         sync.RWMutex
+	watchers   []*keyWatcher
+	validators []keyValidator
+	events     *dispatcher
 }
 
 // NewConfig generates a Config struct
-func NewConfig(name string) (*Config, error) {
+// A vendor-qualified name like "acme/tool" keeps the config in
+// ~/.config/acme/tool/tool.conf, see ValidateAppName.
+func NewConfig(name string, opts ...Option) (*Config, error) {
 	c := &Config{name: name}
+	for _, opt := range opts {
+		opt(c)
+	}
 	if err := c.Load(); err != nil {
 		return c, err
 	}
 	return c, nil
 }
 
-// GetKeyList at the config level returns all keys in the <c.name>.conf file
+// GetKeyList at the config level returns all keys in the <c.name>.conf file,
+// sorted
 func (c *Config) GetKeyList() []string {
-	return c.generalConfig.GetKeyList()
+	return c.general().GetKeyList()
 }
 
 // Set at the config level sets a value in the <c.name>.conf file
 func (c *Config) Set(k, v string) error {
-	return c.generalConfig.Set(k, v)
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.Set(k, v)
+	})
 }
 
-// SetBytes at the config level sets a value in the <c.name>.conf file
+// SetBytes at the config level sets a binary value in the <c.name>.conf file
+// Large values are written to a raw file instead
 func (c *Config) SetBytes(k string, v []byte) error {
-	return c.generalConfig.SetBytes(k, v)
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetBytes(k, v)
+	})
 }
 
 // SetInt saves an integer (as a string) in the <c.name>.conf file
 func (c *Config) SetInt(k string, v int) error {
-	return c.generalConfig.SetInt(k, v)
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetInt(k, v)
+	})
 }
 
 // SetDateTime saves a time.Time (as a string) in the <c.name>.conf file
 func (c *Config) SetDateTime(k string, v time.Time) error {
-	return c.generalConfig.SetDateTime(k, v)
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetDateTime(k, v)
+	})
 }
 
 // SetArray saves a string slice in the <c.name>.conf file
 func (c *Config) SetArray(k string, v []string) error {
-	return c.generalConfig.SetArray(k, v)
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetArray(k, v)
+	})
+}
+
+// SetBool saves a bool (as a string) in the <c.name>.conf file
+func (c *Config) SetBool(k string, v bool) error {
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetBool(k, v)
+	})
+}
+
+// SetFloat saves a float64 (as a string) in the <c.name>.conf file
+func (c *Config) SetFloat(k string, v float64) error {
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetFloat(k, v)
+	})
+}
+
+// SetDuration saves a time.Duration (as a string) in the <c.name>.conf file
+func (c *Config) SetDuration(k string, v time.Duration) error {
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetDuration(k, v)
+	})
+}
+
+// SetURL saves a URL (as a string) in the <c.name>.conf file
+func (c *Config) SetURL(k string, v *url.URL) error {
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetURL(k, v)
+	})
+}
+
+// SetIntArray saves an int slice in the <c.name>.conf file
+func (c *Config) SetIntArray(k string, v []int) error {
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetIntArray(k, v)
+	})
+}
+
+// SetMap saves a string map in the <c.name>.conf file
+func (c *Config) SetMap(k string, v map[string]string) error {
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.SetMap(k, v)
+	})
 }
 
 // Get at the config level retrieves a value from the <c.name>.conf file
+// With WithInterpolation, any references in the value are expanded
 func (c *Config) Get(k string) string {
-	return c.generalConfig.Get(k)
+	return c.general().Get(k)
+}
+
+// GetRaw at the config level retrieves a value from the <c.name>.conf file
+// without expanding any references
+func (c *Config) GetRaw(k string) string {
+	return c.general().GetRaw(k)
+}
+
+// GetExpanded at the config level retrieves a value from the <c.name>.conf
+// file with its references expanded, or an error if they can't be
+func (c *Config) GetExpanded(k string) (string, error) {
+	return c.general().GetExpanded(k)
 }
 
-// GetBytes at the config level retrieves a value from the <c.name>.conf file
-// and returns it as a byte slice
+// GetBytes at the config level retrieves a binary value from the
+// <c.name>.conf file (or its raw file) and returns it as a byte slice
 func (c *Config) GetBytes(k string) []byte {
-	return c.generalConfig.GetBytes(k)
+	return c.general().GetBytes(k)
 }
 
 // GetInt at the config level retrieves a value from the <c.name>.conf file
 // and returns it as an integer (or an error if conversion fails)
 func (c *Config) GetInt(k string) (int, error) {
-	return c.generalConfig.GetInt(k)
+	return c.general().GetInt(k)
 }
 
 // GetDateTime at the config level retrieves a value from the <c.name>.conf file
+// and returns it as a time.Time (or an error if it can't be parsed)
 func (c *Config) GetDateTime(k string) (time.Time, error) {
-	return c.generalConfig.GetDateTime(k)
+	return c.general().GetDateTime(k)
 }
 
 func (c *Config) GetArray(k string) ([]string, error) {
-	return c.generalConfig.GetArray(k)
+	return c.general().GetArray(k)
+}
+
+// Source at the config level returns the file that the value for k comes
+// from, either the <c.name>.conf file or one of the files it includes
+func (c *Config) Source(k string) string {
+	return c.general().Source(k)
+}
+
+// FlatValues at the config level returns every key/value pair in the
+// <c.name>.conf file, so a Config can be passed to Diff and Merge
+func (c *Config) FlatValues() map[string]string {
+	return c.general().FlatValues()
+}
+
+// GetBool at the config level retrieves a value from the <c.name>.conf file
+// and returns it as a bool (or an error if conversion fails)
+func (c *Config) GetBool(k string) (bool, error) {
+	return c.general().GetBool(k)
+}
+
+// GetFloat at the config level retrieves a value from the <c.name>.conf file
+// and returns it as a float64 (or an error if conversion fails)
+func (c *Config) GetFloat(k string) (float64, error) {
+	return c.general().GetFloat(k)
+}
+
+// GetDuration at the config level retrieves a value from the <c.name>.conf
+// file and returns it as a time.Duration (or an error if conversion fails)
+func (c *Config) GetDuration(k string) (time.Duration, error) {
+	return c.general().GetDuration(k)
+}
+
+// GetURL at the config level retrieves a value from the <c.name>.conf file
+// and returns it as a *url.URL (or an error if conversion fails)
+func (c *Config) GetURL(k string) (*url.URL, error) {
+	return c.general().GetURL(k)
+}
+
+// GetIntArray at the config level retrieves a value from the <c.name>.conf
+// file and returns it as an int slice (or an error if conversion fails)
+func (c *Config) GetIntArray(k string) ([]int, error) {
+	return c.general().GetIntArray(k)
+}
+
+// GetMap at the config level retrieves a value from the <c.name>.conf file
+// and returns it as a string map (or an error if conversion fails)
+func (c *Config) GetMap(k string) (map[string]string, error) {
+	return c.general().GetMap(k)
 }
 
 // DeleteKey at the config level removes a key from the <c.name>.conf file
 func (c *Config) DeleteKey(k string) error {
-	return c.generalConfig.DeleteKey(k)
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.DeleteKey(k)
+	})
+}
+
+// GetFullPath returns the full path & filename to the <c.name>.conf file
+func (c *Config) GetFullPath() string {
+	return c.general().GetFullPath()
 }
 
 // GetConfigPath just returns the config path
 func (c *Config) GetConfigPath() string {
-	return c.generalConfig.Path
+	return c.general().Path
+}
+
+// IsReadOnly returns whether the config was opened with ReadOnly
+func (c *Config) IsReadOnly() bool {
+	return c.readOnly
+}
+
+// GetDataDir returns the app's XDG data directory
+func (c *Config) GetDataDir() *AppDir {
+	return c.dataDir
+}
+
+// GetCacheDir returns the app's XDG cache directory
+func (c *Config) GetCacheDir() *AppDir {
+	return c.cacheDir
 }
 
 // Load loads config files into the config
 func (c *Config) Load() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	var err error
-	if strings.TrimSpace(c.name) == "" {
-		return errors.New("Invalid Config Name: " + c.name)
+	if err = ValidateAppName(c.name); err != nil {
+		return &ConfigError{Op: "load", Path: c.name, Err: err}
+	}
+
+	c.naming = c.naming.withDefaults()
+	if err = c.naming.validate(); err != nil {
+		return &ConfigError{Op: "load", Path: c.name, Err: err}
+	}
+	fileName := appFileName(c.name)
+	if c.fileName != "" {
+		if filepath.Ext(c.fileName) != c.naming.GeneralExt {
+			return &ConfigError{Op: "load", Path: c.fileName, Err: ErrInvalidName}
+		}
+		fileName = strings.TrimSuffix(c.fileName, c.naming.GeneralExt)
+	}
+	if err = ValidateFileName(fileName); err != nil {
+		return &ConfigError{Op: "load", Path: c.name, Err: err}
 	}
 
-	var cfgPath string
 	app := xdg.App{Name: c.name}
-	cfgPath = app.ConfigPath("")
-	if cfgPath != "" {
-		if err = c.verifyOrCreateDirectory(cfgPath); err != nil {
+	cfgPath := c.findConfigPath(app, fileName)
+	dataPath, cachePath := c.dataPath, c.cachePath
+	if dataPath == "" {
+		dataPath = app.DataPath("")
+	}
+	if cachePath == "" {
+		cachePath = app.CachePath("")
+	}
+	c.dataDir = NewAppDir(dataPath)
+	c.dataDir.Mode = c.dirMode
+	c.cacheDir = NewAppDir(cachePath)
+	c.cacheDir.Mode = c.dirMode
+	c.addons = make(map[string]*AddonConfig)
+	if c.fs == nil {
+		c.fs = OSFileSystem{}
+	}
+	// Read-only configs don't create anything
+	if cfgPath != "" && !c.readOnly {
+		if err = c.fs.MkdirAll(cfgPath, c.dirMode); err != nil {
 			return err
 		}
+		if _, onDisk := c.fs.(OSFileSystem); onDisk && c.migrate {
+			if _, err = MigrateFileNames(cfgPath, fileName, c.naming); err != nil {
+				return err
+			}
+		}
 	}
 	// Load general config
-	if c.generalConfig, err = NewGeneralConfig(c.name, cfgPath); err != nil {
-		return err
+	gf, err := newGeneralConfig(fileName, cfgPath, c.naming.GeneralExt, c.readOnly, c.fs)
+	if err == nil {
+		err = c.importShared(gf)
 	}
+	c.setGeneral(gf)
+	return err
+}
 
-	return nil
+// general returns the general config, which Reload can swap out from
+// under us
+func (c *Config) general() *GeneralConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generalConfig
+}
+
+// setGeneral hooks gf up to c and makes it the general config
+func (c *Config) setGeneral(gf *GeneralConfig) {
+	gf.interpolate = c.interpolate
+	gf.resolver = c.resolveRef
+	gf.bytesEncoding = c.bytesEncoding
+	gf.blobLimit = c.blobLimit
+	gf.dateTimeLayouts = c.dateTimeLayouts
+	gf.location = c.location
+	if !c.readOnly {
+		gf.onChange = c.changed
+	}
+	c.mu.Lock()
+	c.generalConfig = gf
+	c.mu.Unlock()
+}
+
+// GetAddonConfig returns the addon config name, which has to be listed in
+// additional_config
+func (c *Config) GetAddonConfig(name string) (*AddonConfig, error) {
+	c.mu.RLock()
+	af, ok := c.addons[name]
+	c.mu.RUnlock()
+	if ok {
+		return af, nil
+	}
+	if err := ValidateFileName(name); err != nil {
+		return nil, &ConfigError{Op: "addon", Path: name, Err: err}
+	}
+	listed := false
+	for _, n := range c.general().addonNames() {
+		listed = listed || n == name
+	}
+	if !listed {
+		return nil, &ConfigError{Op: "addon", Path: name, Err: ErrNotExist}
+	}
+	af, err := newAddonConfig(name, c.GetConfigPath(), c.naming.AddonExt, c.readOnly, c.fs)
+	if err != nil {
+		return nil, err
+	}
+	af.bytesEncoding = c.bytesEncoding
+	af.dateTimeLayouts = c.dateTimeLayouts
+	af.location = c.location
+	c.mu.Lock()
+	c.addons[name] = af
+	c.mu.Unlock()
+	return af, nil
+}
+
+// findConfigPath decides which directory the config lives in
+// An explicit directory wins, then the first search path that has the
+// config file in it, then the XDG config directory
+func (c *Config) findConfigPath(app xdg.App, fileName string) string {
+	if c.dir != "" {
+		return c.dir
+	}
+	for _, dir := range c.searchPaths {
+		if _, err := os.Stat(filepath.Join(dir, fileName+c.naming.GeneralExt)); err == nil {
+			return dir
+		}
+	}
+	return app.ConfigPath("")
 }
 
 // Save writes the config to file(s)
 func (c *Config) Save() error {
-	if c.generalConfig == nil {
-		return errors.New("Bad setup.")
+	if c.general() == nil {
+		return &ConfigError{Op: "save", Path: c.name, Err: ErrNotLoaded}
 	}
-	return c.generalConfig.Save()
+	return c.update(func(gf *GeneralConfig) error {
+		return gf.Save()
+	})
 }
 
-// verifyOrCreateDirectory is a helper function for building an
-// individual directory
-func (c *Config) verifyOrCreateDirectory(path string) error {
-	var tstDir *os.File
-	var tstDirInfo os.FileInfo
-	var err error
-	if tstDir, err = os.Open(path); err != nil {
-		if err = os.Mkdir(path, 0755); err != nil {
-			return err
+// update calls fn with the general config while holding c.writeMu, so
+// nothing else changes the config, and Reload doesn't replace it, until fn
+// is done
+func (c *Config) update(fn func(gf *GeneralConfig) error) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return fn(c.general())
+}
+
+// verifyOrCreateDirectory is a helper function for building a directory
+// along with any missing parents. Symlinks are followed, and the error
+// matches ErrNotDirectory or ErrPermission when that's what went wrong.
+func verifyOrCreateDirectory(path string, mode os.FileMode) error {
+	if mode == 0 {
+		mode = DefaultDirMode
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		// Make sure the link actually points somewhere
+		if _, err = filepath.EvalSymlinks(path); err != nil {
+			return dirError(path, err)
 		}
-		if tstDir, err = os.Open(path); err != nil {
-			return err
+	}
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		if err = os.MkdirAll(path, mode); err != nil {
+			return dirError(path, err)
 		}
+		fi, err = os.Stat(path)
 	}
-	if tstDirInfo, err = tstDir.Stat(); err != nil {
-		return err
+	if err != nil {
+		return dirError(path, err)
 	}
-	if !tstDirInfo.IsDir() {
-		return errors.New(path + " exists and is not a directory")
+	if !fi.IsDir() {
+		return &ConfigError{Op: "mkdir", Path: path, Err: ErrNotDirectory}
 	}
-	// We were able to open the path and it was a directory
+	// The path exists and is a directory
 	return nil
 }
+
+// dirError wraps an error from setting up the directory at path so that it
+// can be matched with errors.Is
+func dirError(path string, err error) error {
+	switch {
+	case os.IsPermission(err):
+		err = fmt.Errorf("%w: %w", ErrPermission, err)
+	case errors.Is(err, syscall.ENOTDIR):
+		// Part of the path exists, but isn't a directory
+		err = fmt.Errorf("%w: %w", ErrNotDirectory, err)
+	}
+	return &ConfigError{Op: "mkdir", Path: path, Err: err}
+}
@@ -4,11 +4,14 @@ package userConfig
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
-	"io/ioutil"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -21,12 +24,56 @@ type GeneralConfig struct {
 	Path        string            `toml:"-"`
 	ConfigFiles []string          `toml:"additional_config"`
 	RawFiles    []string          `toml:"raw_files"`
+	Include     []string          `toml:"include,omitempty"`
 	Values      map[string]string `toml:"general"`
+
+	// mu guards the exported fields, layers and overlays, so gf can be
+	// used from more than one goroutine through its methods
+	mu       sync.RWMutex
+	readOnly bool
+	fs       FileSystem
+	// ext is the extension of the file, ".conf" by default
+	ext string
+	// onChange is called after a change has been saved
+	onChange func(Change)
+	// interpolate makes Get expand ${...} references, resolver looks up
+	// the ones with a scheme other than env:
+	interpolate bool
+	resolver    func(scheme, name string) (string, error)
+	// layers are the included, drop-in and shared files, highest priority
+	// first, overlays are the shared files that win over the main file
+	layers   []configLayer
+	overlays []configLayer
+	// bytesEncoding and blobLimit control how SetBytes stores values
+	bytesEncoding BytesEncoding
+	blobLimit     int
+	// dateTimeLayouts and location control how GetDateTime parses values
+	dateTimeLayouts []string
+	location        *time.Location
+}
+
+// generalFile is what's decoded from a general config file
+// Values can be native TOML types in a hand-edited file.
+type generalFile struct {
+	ConfigFiles []string               `toml:"additional_config"`
+	RawFiles    []string               `toml:"raw_files"`
+	Include     []string               `toml:"include"`
+	Values      map[string]interface{} `toml:"general"`
 }
 
 // NewGeneralConfig generates a General Config struct
 func NewGeneralConfig(name, path string) (*GeneralConfig, error) {
-	gf := &GeneralConfig{Name: name, Path: path}
+	return newGeneralConfig(name, path, DefaultNaming.GeneralExt, false, OSFileSystem{})
+}
+
+// NewReadOnlyGeneralConfig generates a General Config struct that never
+// creates or writes its file
+func NewReadOnlyGeneralConfig(name, path string) (*GeneralConfig, error) {
+	return newGeneralConfig(name, path, DefaultNaming.GeneralExt, true, OSFileSystem{})
+}
+
+func newGeneralConfig(name, path, ext string, readOnly bool, fs FileSystem) (*GeneralConfig, error) {
+	gf := &GeneralConfig{Name: name, Path: path, readOnly: readOnly, fs: fs, ext: ext}
 	gf.ConfigFiles = []string{}
 	gf.RawFiles = []string{}
 	gf.Values = make(map[string]string)
@@ -39,59 +86,171 @@ func NewGeneralConfig(name, path string) (*GeneralConfig, error) {
 
 // Load loads config files into the config
 func (gf *GeneralConfig) Load() error {
-	if strings.TrimSpace(gf.Name) == "" || strings.TrimSpace(gf.Path) == "" {
-		return errors.New("Invalid ConfigFile Name: " + gf.Path + string(os.PathSeparator) + gf.Name)
+	cfgPath := gf.GetFullPath()
+	if err := ValidateFileName(gf.Name); err != nil {
+		return &ConfigError{Op: "load", Path: cfgPath, Err: err}
+	}
+	if strings.TrimSpace(gf.Path) == "" {
+		return &ConfigError{Op: "load", Path: cfgPath, Err: ErrInvalidName}
 	}
 
-	// Config files end with .conf
-	cfgPath := gf.Path + string(os.PathSeparator) + gf.Name + ".conf"
-	tomlData, err := ioutil.ReadFile(cfgPath)
+	tomlData, err := gf.fileSystem().ReadFile(cfgPath)
 	if err != nil {
+		if !os.IsNotExist(err) {
+			return &ConfigError{Op: "load", Path: cfgPath, Err: err}
+		}
+		if gf.readOnly {
+			return &ConfigError{Op: "load", Path: cfgPath, Err: fmt.Errorf("%w: %w", ErrNotExist, err)}
+		}
 		// Couldn't find the file, save a new one
 		if err = gf.Save(); err != nil {
 			return err
 		}
 	}
-	if _, err := toml.Decode(string(tomlData), &gf); err != nil {
-		return err
+	var file generalFile
+	if _, err := decodeTOML(string(tomlData), &file); err != nil {
+		return &ConfigError{Op: "load", Path: cfgPath, Err: newParseError(cfgPath, err)}
 	}
-	return nil
+	layers, err := gf.readLayers(file.Include)
+
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	if file.ConfigFiles != nil {
+		gf.ConfigFiles = file.ConfigFiles
+	}
+	if file.RawFiles != nil {
+		gf.RawFiles = file.RawFiles
+	}
+	gf.Include = file.Include
+	gf.Values = tomlValues(file.Values)
+	gf.layers = layers
+	return err
 }
 
 // Save writes the config to file(s)
 func (gf *GeneralConfig) Save() error {
+	if gf.readOnly {
+		return &ConfigError{Op: "save", Path: gf.GetFullPath(), Err: ErrReadOnly}
+	}
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	if err := gf.write(); err != nil {
+		return &ConfigError{Op: "save", Path: gf.GetFullPath(), Err: err}
+	}
+	return nil
+}
+
+// write encodes the config and writes it to its file, gf.mu has to be held
+func (gf *GeneralConfig) write() error {
 	buf := new(bytes.Buffer)
-	cfgPath := gf.Path + string(os.PathSeparator) + gf.Name + ".conf"
 	if err := toml.NewEncoder(buf).Encode(gf); err != nil {
 		return err
 	}
-	return ioutil.WriteFile(cfgPath, buf.Bytes(), 0644)
+	return gf.fileSystem().WriteFile(gf.GetFullPath(), buf.Bytes(), 0644)
+}
+
+// fileSystem returns what gf's file is read and written with
+func (gf *GeneralConfig) fileSystem() FileSystem {
+	if gf.fs == nil {
+		return OSFileSystem{}
+	}
+	return gf.fs
 }
 
-// GetKeyList returns a list of all keys in the config file
+// GetName returns the name of this config file
+func (gf *GeneralConfig) GetName() string {
+	return gf.Name
+}
+
+// GetPath returns the path of this config file
+func (gf *GeneralConfig) GetPath() string {
+	return gf.Path
+}
+
+// IsReadOnly returns whether gf was opened read-only
+func (gf *GeneralConfig) IsReadOnly() bool {
+	return gf.readOnly
+}
+
+// GetFullPath returns the full path & filename to the config file
+// Config files end with .conf, unless the NamingPolicy says otherwise
+func (gf *GeneralConfig) GetFullPath() string {
+	return filepath.Join(gf.Path, gf.Name+gf.extension())
+}
+
+// extension returns the extension of gf's file
+func (gf *GeneralConfig) extension() string {
+	if gf.ext == "" {
+		return DefaultNaming.GeneralExt
+	}
+	return gf.ext
+}
+
+// keyError wraps err with the operation and key that caused it
+func (gf *GeneralConfig) keyError(op, k string, err error) error {
+	return &ConfigError{Op: op, Path: gf.GetFullPath(), Key: k, Err: err}
+}
+
+// GetKeyList returns a sorted list of all keys in the config file and the
+// files it includes
 func (gf *GeneralConfig) GetKeyList() []string {
+	gf.mu.RLock()
+	defer gf.mu.RUnlock()
+	return gf.keyList()
+}
+
+// keyList is GetKeyList for callers that hold gf.mu
+func (gf *GeneralConfig) keyList() []string {
 	var ret []string
+	seen := make(map[string]bool)
 	for k, _ := range gf.Values {
+		seen[k] = true
 		ret = append(ret, k)
 	}
+	for _, l := range append(append([]configLayer{}, gf.overlays...), gf.layers...) {
+		for k := range l.values {
+			if !seen[k] {
+				seen[k] = true
+				ret = append(ret, k)
+			}
+		}
+	}
+	sort.Strings(ret)
 	return ret
 }
 
 // Set sets a key/value pair in gf, if unable to save, revert to old value
 // (and return the error)
 func (gf *GeneralConfig) Set(k, v string) error {
-	oldVal := gf.Values[k]
-	gf.Values[k] = v
-	if err := gf.Save(); err != nil {
-		gf.Values[k] = oldVal
+	gf.mu.Lock()
+	ch, err := gf.set(k, v)
+	gf.mu.Unlock()
+	if err != nil {
 		return err
 	}
+	gf.changed(ch)
 	return nil
 }
 
-// SetBytes at the config level sets a value in the <c.name>.conf file
-func (gf *GeneralConfig) SetBytes(k string, v []byte) error {
-	return gf.Set(k, string(v))
+// set is Set for callers that hold gf.mu, it returns the change for them
+// to pass on once they've let go of it
+func (gf *GeneralConfig) set(k, v string) (Change, error) {
+	if gf.readOnly || gf.shadowed(k) {
+		return Change{}, gf.keyError("set", k, ErrReadOnly)
+	}
+	oldVal, existed := gf.Values[k]
+	blob, oldRaw := gf.releaseBlob(oldVal, v)
+	gf.Values[k] = v
+	if err := gf.write(); err != nil {
+		if existed {
+			gf.Values[k] = oldVal
+		} else {
+			delete(gf.Values, k)
+		}
+		gf.RawFiles = oldRaw
+		return Change{}, gf.keyError("set", k, err)
+	}
+	return Change{Key: k, Old: oldVal, New: v, Existed: existed, released: blob}, nil
 }
 
 // SetInt sets an integer value (as a string) in the config file
@@ -99,56 +258,216 @@ func (gf *GeneralConfig) SetInt(k string, v int) error {
 	return gf.Set(k, strconv.Itoa(v))
 }
 
-// SetDateTime sets a DateTime value (as a string) in the config file
+// SetDateTime sets a DateTime value (as an RFC3339 string, keeping any
+// fraction of a second) in the config file
 func (gf *GeneralConfig) SetDateTime(k string, v time.Time) error {
-	return gf.Set(k, v.Format(time.RFC3339))
+	return gf.Set(k, formatDateTime(v))
 }
 
 // SetArray sets a string slice value (as a string) in the config file
 func (gf *GeneralConfig) SetArray(k string, v []string) error {
 	b, e := json.Marshal(v)
 	if e != nil {
-		return e
+		return gf.keyError("set", k, e)
+	}
+	return gf.Set(k, string(b))
+}
+
+// SetBool sets a boolean value (as a string) in the config file
+func (gf *GeneralConfig) SetBool(k string, v bool) error {
+	return gf.Set(k, strconv.FormatBool(v))
+}
+
+// SetFloat sets a float value (as a string) in the config file
+func (gf *GeneralConfig) SetFloat(k string, v float64) error {
+	return gf.Set(k, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+// SetDuration sets a time.Duration value (as a string) in the config file
+func (gf *GeneralConfig) SetDuration(k string, v time.Duration) error {
+	return gf.Set(k, v.String())
+}
+
+// SetURL sets a URL value (as a string) in the config file
+func (gf *GeneralConfig) SetURL(k string, v *url.URL) error {
+	if v == nil {
+		return gf.keyError("set", k, errNilURL)
+	}
+	return gf.Set(k, v.String())
+}
+
+// SetIntArray sets an int slice value (as a string) in the config file
+func (gf *GeneralConfig) SetIntArray(k string, v []int) error {
+	b, e := json.Marshal(v)
+	if e != nil {
+		return gf.keyError("set", k, e)
+	}
+	return gf.Set(k, string(b))
+}
+
+// SetMap sets a string map value (as a string) in the config file
+func (gf *GeneralConfig) SetMap(k string, v map[string]string) error {
+	b, e := json.Marshal(v)
+	if e != nil {
+		return gf.keyError("set", k, e)
 	}
-	return gf.SetBytes(k, b)
+	return gf.Set(k, string(b))
 }
 
 // Get gets a key/value pair from gf
+// If interpolation is on, references are expanded. A value that can't be
+// expanded is returned as is.
 func (gf *GeneralConfig) Get(k string) string {
-	return gf.Values[k]
+	if gf.interpolate {
+		if v, err := gf.GetExpanded(k); err == nil {
+			return v
+		}
+	}
+	return gf.GetRaw(k)
+}
+
+// SetInterpolation turns expanding ${...} references in Get on or off
+func (gf *GeneralConfig) SetInterpolation(on bool) {
+	gf.interpolate = on
 }
 
 // GetInt gets a key/value pair from gf and return it as an integer
 // An error if it can't be converted
 func (gf *GeneralConfig) GetInt(k string) (int, error) {
-	return strconv.Atoi(gf.Get(k))
+	v, err := strconv.Atoi(gf.Get(k))
+	if err != nil {
+		return v, gf.keyError("get", k, err)
+	}
+	return v, nil
 }
 
 // GetDateTime gets a key/value pair from gf and returns it as a time.Time
+// Any of the accepted layouts can be used, see DefaultDateTimeLayouts
 // An error if it can't be converted
 func (gf *GeneralConfig) GetDateTime(k string) (time.Time, error) {
-	return time.Parse(time.RFC3339, gf.Get(k))
-}
-
-// GetBytes gets a key/value pair from gf and returns it as a byte slice
-// Or an error if it fails for whatever reason
-func (gf *GeneralConfig) GetBytes(k string) []byte {
-	return []byte(gf.Get(k))
+	v, err := parseDateTime(gf.Get(k), gf.dateTimeLayouts, gf.location)
+	if err != nil {
+		return v, gf.keyError("get", k, err)
+	}
+	return v, nil
 }
 
 func (gf *GeneralConfig) GetArray(k string) ([]string, error) {
 	var ret []string
-	err := json.Unmarshal(gf.GetBytes(k), &ret)
-	return ret, err
+	if err := json.Unmarshal([]byte(gf.Get(k)), &ret); err != nil {
+		return ret, gf.keyError("get", k, err)
+	}
+	return ret, nil
+}
+
+// GetBool gets a key/value pair from gf and returns it as a bool
+// An error if it can't be converted
+func (gf *GeneralConfig) GetBool(k string) (bool, error) {
+	v, err := strconv.ParseBool(gf.Get(k))
+	if err != nil {
+		return v, gf.keyError("get", k, err)
+	}
+	return v, nil
+}
+
+// GetFloat gets a key/value pair from gf and returns it as a float64
+// An error if it can't be converted
+func (gf *GeneralConfig) GetFloat(k string) (float64, error) {
+	v, err := strconv.ParseFloat(gf.Get(k), 64)
+	if err != nil {
+		return v, gf.keyError("get", k, err)
+	}
+	return v, nil
+}
+
+// GetDuration gets a key/value pair from gf and returns it as a
+// time.Duration
+// An error if it can't be converted
+func (gf *GeneralConfig) GetDuration(k string) (time.Duration, error) {
+	v, err := time.ParseDuration(gf.Get(k))
+	if err != nil {
+		return v, gf.keyError("get", k, err)
+	}
+	return v, nil
+}
+
+// GetURL gets a key/value pair from gf and returns it as a *url.URL
+// An error if it can't be converted
+func (gf *GeneralConfig) GetURL(k string) (*url.URL, error) {
+	v, err := url.Parse(gf.Get(k))
+	if err != nil {
+		return v, gf.keyError("get", k, err)
+	}
+	return v, nil
+}
+
+// GetIntArray gets a key/value pair from gf and returns it as an int slice
+// An error if it can't be converted
+func (gf *GeneralConfig) GetIntArray(k string) ([]int, error) {
+	var ret []int
+	if err := json.Unmarshal([]byte(gf.Get(k)), &ret); err != nil {
+		return ret, gf.keyError("get", k, err)
+	}
+	return ret, nil
+}
+
+// GetMap gets a key/value pair from gf and returns it as a string map
+// An error if it can't be converted
+func (gf *GeneralConfig) GetMap(k string) (map[string]string, error) {
+	var ret map[string]string
+	if err := json.Unmarshal([]byte(gf.Get(k)), &ret); err != nil {
+		return ret, gf.keyError("get", k, err)
+	}
+	return ret, nil
 }
 
 // DeleteKey removes a key from the file
 func (gf *GeneralConfig) DeleteKey(k string) error {
-	oldVal := gf.Get(k)
-	delete(gf.Values, k)
-	if err := gf.Save(); err != nil {
-		gf.Values[k] = oldVal
+	gf.mu.Lock()
+	ch, err := gf.deleteKey(k)
+	gf.mu.Unlock()
+	if err != nil {
 		return err
 	}
+	if ch.Existed {
+		gf.changed(ch)
+	}
 	return nil
 }
+
+// deleteKey is DeleteKey for callers that hold gf.mu
+func (gf *GeneralConfig) deleteKey(k string) (Change, error) {
+	if gf.readOnly || gf.shadowed(k) {
+		return Change{}, gf.keyError("delete", k, ErrReadOnly)
+	}
+	oldVal, existed := gf.Values[k]
+	blob, oldRaw := gf.releaseBlob(oldVal, "")
+	delete(gf.Values, k)
+	if err := gf.write(); err != nil {
+		if existed {
+			gf.Values[k] = oldVal
+		}
+		gf.RawFiles = oldRaw
+		return Change{}, gf.keyError("delete", k, err)
+	}
+	return Change{Key: k, Old: oldVal, Existed: existed, Deleted: true, released: blob}, nil
+}
+
+// addonNames returns a copy of the addons listed in additional_config
+func (gf *GeneralConfig) addonNames() []string {
+	gf.mu.RLock()
+	defer gf.mu.RUnlock()
+	return append([]string{}, gf.ConfigFiles...)
+}
+
+// changed passes a saved change on to the onChange hook
+// Without one there's no journal, so a raw file the change released goes
+// right away.
+func (gf *GeneralConfig) changed(ch Change) {
+	if gf.onChange == nil {
+		gf.removeBlobs([]string{ch.released}, nil)
+		return
+	}
+	ch.Time = time.Now()
+	gf.onChange(ch)
+}
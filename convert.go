@@ -0,0 +1,145 @@
+package userConfig
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoConverter is returned by Get and Set for a type that doesn't have a
+// converter registered
+var ErrNoConverter = errors.New("no converter registered for type")
+
+// errNilURL is returned when a nil *url.URL is set
+var errNilURL = errors.New("nil URL")
+
+// converter turns values of one type to and from the strings stored in a
+// config file
+type converter struct {
+	decode func(string) (interface{}, error)
+	encode func(interface{}) (string, error)
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(map[reflect.Type]converter)
+)
+
+// RegisterConverter lets Get and Set handle values of type T, like IP
+// addresses, regexps or file sizes. Registering a type again replaces its
+// converter.
+func RegisterConverter[T any](decode func(string) (T, error), encode func(T) (string, error)) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters[reflect.TypeOf((*T)(nil)).Elem()] = converter{
+		decode: func(s string) (interface{}, error) {
+			return decode(s)
+		},
+		encode: func(v interface{}) (string, error) {
+			return encode(v.(T))
+		},
+	}
+}
+
+// lookupConverter returns the converter for type T
+func lookupConverter[T any]() (converter, bool) {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	conv, ok := converters[reflect.TypeOf((*T)(nil)).Elem()]
+	return conv, ok
+}
+
+// Get retrieves a value from c's <name>.conf file and converts it to a T
+// using the registered converter
+// A []byte is read with GetBytes and a time.Time with GetDateTime.
+func Get[T any](c *Config, k string) (T, error) {
+	var ret T
+	switch any(ret).(type) {
+	case []byte:
+		return any(c.GetBytes(k)).(T), nil
+	case time.Time:
+		t, err := c.GetDateTime(k)
+		return any(t).(T), err
+	}
+	conv, ok := lookupConverter[T]()
+	if !ok {
+		return ret, c.general().keyError("get", k, ErrNoConverter)
+	}
+	v, err := conv.decode(c.Get(k))
+	if err != nil {
+		return ret, c.general().keyError("get", k, err)
+	}
+	return v.(T), nil
+}
+
+// Set converts v to a string using the registered converter and saves it
+// in c's <name>.conf file
+// A []byte is saved with SetBytes and a time.Time with SetDateTime.
+func Set[T any](c *Config, k string, v T) error {
+	switch tv := any(v).(type) {
+	case []byte:
+		return c.SetBytes(k, tv)
+	case time.Time:
+		return c.SetDateTime(k, tv)
+	}
+	conv, ok := lookupConverter[T]()
+	if !ok {
+		return c.general().keyError("set", k, ErrNoConverter)
+	}
+	s, err := conv.encode(v)
+	if err != nil {
+		return c.general().keyError("set", k, err)
+	}
+	return c.Set(k, s)
+}
+
+// decodeJSON decodes a JSON encoded value
+func decodeJSON[T any](s string) (T, error) {
+	var ret T
+	err := json.Unmarshal([]byte(s), &ret)
+	return ret, err
+}
+
+// encodeJSON encodes a value as JSON
+func encodeJSON[T any](v T) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// The types with their own getters and setters are stored the same way
+// []byte and time.Time are handled by Get and Set themselves so they follow
+// the config's blob and date/time settings
+func init() {
+	RegisterConverter(func(s string) (string, error) {
+		return s, nil
+	}, func(v string) (string, error) {
+		return v, nil
+	})
+	RegisterConverter(strconv.Atoi, func(v int) (string, error) {
+		return strconv.Itoa(v), nil
+	})
+	RegisterConverter(strconv.ParseBool, func(v bool) (string, error) {
+		return strconv.FormatBool(v), nil
+	})
+	RegisterConverter(func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	}, func(v float64) (string, error) {
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	})
+	RegisterConverter(time.ParseDuration, func(v time.Duration) (string, error) {
+		return v.String(), nil
+	})
+	RegisterConverter(url.Parse, func(v *url.URL) (string, error) {
+		if v == nil {
+			return "", errNilURL
+		}
+		return v.String(), nil
+	})
+	RegisterConverter(decodeJSON[[]string], encodeJSON[[]string])
+	RegisterConverter(decodeJSON[[]int], encodeJSON[[]int])
+	RegisterConverter(decodeJSON[map[string]string], encodeJSON[map[string]string])
+}
@@ -0,0 +1,210 @@
+package userConfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultDateTimeLayouts are the layouts GetDateTime accepts, in the order
+// they're tried. Layouts without a zone are read in the config's location.
+var DefaultDateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"15:04:05.999999999",
+	"15:04",
+}
+
+// parseDateTime parses v with the first of layouts that fits, in loc
+func parseDateTime(v string, layouts []string, loc *time.Location) (time.Time, error) {
+	if len(layouts) == 0 {
+		layouts = DefaultDateTimeLayouts
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q doesn't match any accepted date/time layout", v)
+}
+
+// formatDateTime formats t so that parseDateTime gets back the same instant,
+// down to the nanosecond
+func formatDateTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// tomlValues turns the values of a decoded table into the strings
+// we keep them as. Hand-edited files can use native TOML types, and they're
+// written back out as strings.
+// Native dates, datetimes and times are read as they're written (see
+// decodeTOML), so local ones stay local and offsets are kept.
+func tomlValues(raw map[string]interface{}) map[string]string {
+	ret := make(map[string]string)
+	for k, v := range raw {
+		ret[k] = tomlValueString(v)
+	}
+	return ret
+}
+
+// tomlValueString converts a single native TOML value to a string
+func tomlValueString(v interface{}) string {
+	switch tv := v.(type) {
+	case string:
+		return tv
+	case int64:
+		return strconv.FormatInt(tv, 10)
+	case float64:
+		return strconv.FormatFloat(tv, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(tv)
+	case time.Time:
+		return formatDateTime(tv)
+	}
+	// Arrays and tables are stored the same way SetArray and SetMap do
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// tomlDateTimeRegexp matches a native TOML date, datetime or local time
+var tomlDateTimeRegexp = regexp.MustCompile(
+	`^(?:\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?)?|\d{2}:\d{2}:\d{2}(?:\.\d+)?)`)
+
+// decodeTOML decodes data into v, reading native dates and times as the
+// strings they're written as
+// The vendored decoder reads local datetimes in the machine's zone, so they
+// can't be told apart from offset ones afterwards, and it can't read local
+// times, "+hh:mm" offsets or a space between the date and time at all.
+func decodeTOML(data string, v interface{}) (toml.MetaData, error) {
+	return toml.Decode(quoteDateTimes(data), v)
+}
+
+// quoteDateTimes puts quotes around the native dates and times used as
+// values (or in arrays) in data, leaving strings, comments and keys alone
+// Line numbers are kept so parse errors still point at the right place.
+func quoteDateTimes(data string) string {
+	var b strings.Builder
+	const (
+		none = iota
+		multiBasic
+		multiLiteral
+	)
+	state := none
+	depth := 0
+	expectValue := false
+	for i := 0; i < len(data); {
+		ch := data[i]
+		rest := data[i:]
+		switch state {
+		case multiBasic:
+			switch {
+			case ch == '\\' && i+1 < len(data):
+				b.WriteString(data[i : i+2])
+				i += 2
+			case strings.HasPrefix(rest, `"""`):
+				b.WriteString(`"""`)
+				i += 3
+				state = none
+			default:
+				b.WriteByte(ch)
+				i++
+			}
+			continue
+		case multiLiteral:
+			if strings.HasPrefix(rest, "'''") {
+				b.WriteString("'''")
+				i += 3
+				state = none
+			} else {
+				b.WriteByte(ch)
+				i++
+			}
+			continue
+		}
+
+		n := 1
+		switch {
+		case ch == '#':
+			n = strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+		case strings.HasPrefix(rest, `"""`):
+			n, state, expectValue = 3, multiBasic, false
+		case strings.HasPrefix(rest, "'''"):
+			n, state, expectValue = 3, multiLiteral, false
+		case ch == '"':
+			for n < len(rest) && rest[n] != '"' && rest[n] != '\n' {
+				if rest[n] == '\\' {
+					n++
+				}
+				n++
+			}
+			n++
+			expectValue = false
+		case ch == '\'':
+			for n < len(rest) && rest[n] != '\'' && rest[n] != '\n' {
+				n++
+			}
+			n++
+			expectValue = false
+		case ch == '=':
+			expectValue = true
+		case ch == '[' && (expectValue || depth > 0):
+			depth++
+			expectValue = true
+		case ch == ']' && depth > 0:
+			depth--
+			expectValue = false
+		case ch == ',' && depth > 0:
+			expectValue = true
+		case ch == '\n':
+			if depth == 0 {
+				expectValue = false
+			}
+		case ch == ' ' || ch == '\t' || ch == '\r':
+		case expectValue && ch >= '0' && ch <= '9':
+			expectValue = false
+			if m := tomlDateTimeRegexp.FindString(rest); m != "" && !isValueChar(rest, len(m)) {
+				b.WriteString(`"` + m + `"`)
+				i += len(m)
+				continue
+			}
+		default:
+			expectValue = false
+		}
+		if n > len(rest) {
+			n = len(rest)
+		}
+		b.WriteString(rest[:n])
+		i += n
+	}
+	return b.String()
+}
+
+// isValueChar reports whether s has a character at i that could carry on
+// a bare value, so a match ending there isn't the whole token
+func isValueChar(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	ch := s[i]
+	return ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' ||
+		ch == '_' || ch == '-' || ch == ':' || ch == '.' || ch == '+'
+}
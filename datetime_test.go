@@ -0,0 +1,47 @@
+package userConfig_test
+
+import (
+	"testing"
+	"time"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestNativeDateTimes(t *testing.T) {
+	// Offsets that happen to match the machine's zone have to survive too
+	_, offset := time.Now().Zone()
+	local := time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", offset)).Format(time.RFC3339)
+	c := userconfigtest.FromTOML(t, "app", `[general]
+utc = 1979-05-27T07:32:00Z
+west = 1979-05-27T07:32:00-07:00
+east = 1979-05-27T07:32:00+05:30
+machine = `+local+`
+floating = 1979-05-27T07:32:00
+day = 1979-05-27
+alarm = 09:00:00
+quoted = "1979-05-27T07:32:00Z" # stays a string
+`, userConfig.WithLocation(time.UTC))
+
+	for k, want := range map[string]string{
+		"utc":      "1979-05-27T07:32:00Z",
+		"west":     "1979-05-27T14:32:00Z",
+		"east":     "1979-05-27T02:02:00Z",
+		"machine":  time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC).Add(-time.Duration(offset) * time.Second).Format(time.RFC3339),
+		"floating": "1979-05-27T07:32:00Z",
+		"day":      "1979-05-27T00:00:00Z",
+		"quoted":   "1979-05-27T07:32:00Z",
+	} {
+		got, err := c.GetDateTime(k)
+		if err != nil {
+			t.Errorf("%s: %v", k, err)
+			continue
+		}
+		if s := got.UTC().Format(time.RFC3339); s != want {
+			t.Errorf("%s = %s, want %s", k, s, want)
+		}
+	}
+	if v := c.Get("alarm"); v != "09:00:00" {
+		t.Errorf("alarm = %q, want 09:00:00", v)
+	}
+}
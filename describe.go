@@ -0,0 +1,99 @@
+package userConfig
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoKey is returned when describing a key that isn't set or declared
+var ErrNoKey = errors.New("key not found")
+
+// KeySchema declares what a key is expected to hold
+type KeySchema struct {
+	Type        string
+	Description string
+}
+
+// KeyInfo describes a key in the config
+// Declared is whether Type came from a KeySchema rather than being
+// inferred from the value.
+type KeyInfo struct {
+	Key         string
+	Type        string
+	Declared    bool
+	Source      string
+	ModTime     time.Time
+	Description string
+}
+
+// Declare sets the schema for key k
+func (c *Config) Declare(k string, s KeySchema) {
+	if c.schema == nil {
+		c.schema = make(map[string]KeySchema)
+	}
+	c.schema[k] = s
+}
+
+// Describe returns the type, source file, last modification time and
+// schema description of key k
+// The modification time comes from the change journal when k is in it,
+// otherwise it's the modification time of the source file.
+func (c *Config) Describe(k string) (*KeyInfo, error) {
+	if c.general() == nil {
+		return nil, &ConfigError{Op: "describe", Path: c.name, Key: k, Err: ErrNotLoaded}
+	}
+	raw, set := c.general().lookup(k)
+	s, declared := c.schema[k]
+	if !set && !declared {
+		return nil, c.general().keyError("describe", k, ErrNoKey)
+	}
+
+	info := &KeyInfo{
+		Key:         k,
+		Type:        s.Type,
+		Declared:    declared && s.Type != "",
+		Source:      c.general().Source(k),
+		Description: s.Description,
+	}
+	if !info.Declared {
+		info.Type = inferType(raw)
+	}
+	if changes, err := c.History(k); err == nil && len(changes) > 0 {
+		info.ModTime = changes[len(changes)-1].Time
+	} else if info.Source != "" {
+		if fi, err := os.Stat(info.Source); err == nil {
+			info.ModTime = fi.ModTime()
+		}
+	}
+	return info, nil
+}
+
+// inferType guesses the type of a value from what it can be parsed as
+func inferType(v string) string {
+	if _, err := strconv.Atoi(v); err == nil {
+		return "int"
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return "float"
+	}
+	if v == "true" || v == "false" {
+		return "bool"
+	}
+	if _, err := time.ParseDuration(v); err == nil {
+		return "duration"
+	}
+	if _, err := parseDateTime(v, nil, nil); err == nil {
+		return "datetime"
+	}
+	if strings.HasPrefix(v, "[") {
+		var arr []interface{}
+		if err := json.Unmarshal([]byte(v), &arr); err == nil {
+			return "array"
+		}
+	}
+	return "string"
+}
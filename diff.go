@@ -0,0 +1,137 @@
+package userConfig
+
+import "sort"
+
+// ValueSource is a config file that can be compared with Diff and Merge
+type ValueSource interface {
+	// FlatValues returns every key in the file along with its value
+	FlatValues() map[string]string
+}
+
+// FlatValues returns a copy of every key/value pair in gf
+func (gf *GeneralConfig) FlatValues() map[string]string {
+	gf.mu.RLock()
+	defer gf.mu.RUnlock()
+	ret := make(map[string]string)
+	for k, v := range gf.Values {
+		ret[k] = v
+	}
+	return ret
+}
+
+// FlatValues returns every value in af keyed by "<category>.<key>"
+func (af *AddonConfig) FlatValues() map[string]string {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	ret := make(map[string]string)
+	for cat, vals := range af.Values {
+		for k, v := range vals {
+			ret[cat+"."+k] = v
+		}
+	}
+	return ret
+}
+
+// KeyDiff is a key that differs between two config files
+type KeyDiff struct {
+	Key string
+	Old string
+	New string
+}
+
+// DiffResult lists the keys that were added, removed and changed going from
+// one config file to another, each sorted by key
+type DiffResult struct {
+	Added   []KeyDiff
+	Removed []KeyDiff
+	Changed []KeyDiff
+}
+
+// Empty returns whether the two files had the same values
+func (d *DiffResult) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the values in a to the values in b
+func Diff(a, b ValueSource) *DiffResult {
+	av, bv := a.FlatValues(), b.FlatValues()
+	d := new(DiffResult)
+	for _, k := range unionKeys(av, bv) {
+		oldVal, inA := av[k]
+		newVal, inB := bv[k]
+		switch {
+		case !inA:
+			d.Added = append(d.Added, KeyDiff{Key: k, New: newVal})
+		case !inB:
+			d.Removed = append(d.Removed, KeyDiff{Key: k, Old: oldVal})
+		case oldVal != newVal:
+			d.Changed = append(d.Changed, KeyDiff{Key: k, Old: oldVal, New: newVal})
+		}
+	}
+	return d
+}
+
+// MergeConflict is a key that both sides of a merge changed differently
+// The In* fields say whether the key existed on that side at all.
+type MergeConflict struct {
+	Key      string
+	Base     string
+	Ours     string
+	Theirs   string
+	InBase   bool
+	InOurs   bool
+	InTheirs bool
+}
+
+// MergeResult is the outcome of a three-way merge
+// Values holds our value for every conflicting key.
+type MergeResult struct {
+	Values    map[string]string
+	Conflicts []MergeConflict
+}
+
+// Merge does a three-way merge of the changes made in ours and theirs since
+// base
+func Merge(base, ours, theirs ValueSource) *MergeResult {
+	bv, ov, tv := base.FlatValues(), ours.FlatValues(), theirs.FlatValues()
+	res := &MergeResult{Values: make(map[string]string)}
+	for _, k := range unionKeys(bv, ov, tv) {
+		b, inB := bv[k]
+		o, inO := ov[k]
+		t, inT := tv[k]
+		switch {
+		case inO == inT && o == t:
+			// Both sides agree
+		case inO == inB && o == b:
+			// Only they changed it
+			o, inO = t, inT
+		case inT == inB && t == b:
+			// Only we changed it
+		default:
+			res.Conflicts = append(res.Conflicts, MergeConflict{
+				Key: k, Base: b, Ours: o, Theirs: t,
+				InBase: inB, InOurs: inO, InTheirs: inT,
+			})
+		}
+		if inO {
+			res.Values[k] = o
+		}
+	}
+	return res
+}
+
+// unionKeys returns every key in any of maps, sorted
+func unionKeys(maps ...map[string]string) []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				ret = append(ret, k)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
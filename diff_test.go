@@ -0,0 +1,55 @@
+package userConfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+// values is a ValueSource for a fixed set of values
+type values map[string]string
+
+func (v values) FlatValues() map[string]string {
+	ret := make(map[string]string)
+	for k, val := range v {
+		ret[k] = val
+	}
+	return ret
+}
+
+func TestMerge(t *testing.T) {
+	base := values{"a": "1", "b": "1", "c": "1", "d": "1", "f": "1"}
+	ours := values{"a": "2", "b": "1", "c": "3", "f": "1"}
+	theirs := values{"a": "1", "b": "5", "c": "4", "d": "1", "e": "new", "f": "2"}
+	ours["same"], theirs["same"] = "x", "x"
+	delete(ours, "f")
+
+	res := userConfig.Merge(base, ours, theirs)
+	want := map[string]string{"a": "2", "b": "5", "c": "3", "e": "new", "same": "x"}
+	if !reflect.DeepEqual(res.Values, want) {
+		t.Errorf("merged values = %v, want %v", res.Values, want)
+	}
+	wantConflicts := []userConfig.MergeConflict{
+		{Key: "c", Base: "1", Ours: "3", Theirs: "4", InBase: true, InOurs: true, InTheirs: true},
+		{Key: "f", Base: "1", Theirs: "2", InBase: true, InTheirs: true},
+	}
+	if !reflect.DeepEqual(res.Conflicts, wantConflicts) {
+		t.Errorf("conflicts = %+v, want %+v", res.Conflicts, wantConflicts)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	d := userConfig.Diff(values{"a": "1", "b": "1"}, values{"b": "2", "c": "3"})
+	want := &userConfig.DiffResult{
+		Added:   []userConfig.KeyDiff{{Key: "c", New: "3"}},
+		Removed: []userConfig.KeyDiff{{Key: "a", Old: "1"}},
+		Changed: []userConfig.KeyDiff{{Key: "b", Old: "1", New: "2"}},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("Diff = %+v, want %+v", d, want)
+	}
+	if !userConfig.Diff(values{"a": "1"}, values{"a": "1"}).Empty() {
+		t.Error("Diff of equal values isn't empty")
+	}
+}
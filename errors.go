@@ -0,0 +1,103 @@
+package userConfig
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// ErrInvalidName is returned when a config or config file name can't
+	// be used
+	ErrInvalidName = errors.New("invalid name")
+	// ErrNotLoaded is returned when a Config is used before it has been
+	// loaded
+	ErrNotLoaded = errors.New("config not loaded")
+	// ErrNotDirectory is returned when a path that should be a directory
+	// exists but isn't one
+	ErrNotDirectory = errors.New("not a directory")
+	// ErrPermission is returned when a directory can't be accessed or
+	// created because of its permissions
+	ErrPermission = errors.New("permission denied")
+	// ErrNotExist is returned when a config that has to exist doesn't, like
+	// one opened read-only
+	ErrNotExist = errors.New("config does not exist")
+	// ErrReadOnly is returned when changing a config opened read-only
+	ErrReadOnly = errors.New("config is read-only")
+)
+
+// ConfigError records an error along with the operation, file and key
+// that caused it
+type ConfigError struct {
+	Op   string
+	Path string
+	Key  string
+	Err  error
+}
+
+// Error returns the error as a string
+// The path is left out when the error is a ParseError for the same file,
+// which starts with it.
+func (e *ConfigError) Error() string {
+	s := e.Op
+	var pe *ParseError
+	if e.Path != "" && !(errors.As(e.Err, &pe) && pe.Path == e.Path && e.Key == "") {
+		s += " " + e.Path
+	}
+	if e.Key != "" {
+		s += " [" + e.Key + "]"
+	}
+	return s + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned when a config file isn't valid TOML
+// The decoder only reports the line an error is near, not the column.
+type ParseError struct {
+	Path string
+	Line int
+	// Err is the decoder's error
+	Err error
+	// msg is Err's message without the position, which is in Line
+	msg string
+}
+
+// Error returns the error as a string
+func (e *ParseError) Error() string {
+	s := e.Path
+	if e.Line > 0 {
+		s += ":" + strconv.Itoa(e.Line)
+	}
+	if e.msg != "" {
+		return s + ": " + e.msg
+	}
+	return s + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// The toml decoder only reports positions as part of its message, like
+// "Near line 3 (last key parsed 'x'): expected ..."
+var tomlLineRegexp = regexp.MustCompile(`^Near line (\d+) \(last key parsed '([^']*)'\): `)
+
+// newParseError builds a ParseError for an error from the toml decoder,
+// moving the line out of the message
+func newParseError(path string, err error) *ParseError {
+	pe := &ParseError{Path: path, Err: err}
+	msg := err.Error()
+	if m := tomlLineRegexp.FindStringSubmatchIndex(msg); m != nil {
+		pe.Line, _ = strconv.Atoi(msg[m[2]:m[3]])
+		pe.msg = msg[m[1]:]
+		if key := msg[m[4]:m[5]]; key != "" {
+			pe.msg += " (after key " + key + ")"
+		}
+	}
+	return pe
+}
@@ -0,0 +1,31 @@
+package userConfig_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestParseError(t *testing.T) {
+	c, fs := userconfigtest.NewFaulty(t, "app")
+	fs.CorruptReads()
+	err := c.Reload()
+	var pe *userConfig.ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Reload of a corrupt file returned %v, want a ParseError", err)
+	}
+	if pe.Line == 0 {
+		t.Errorf("ParseError has no line: %v", pe)
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "Near line") || strings.Count(msg, c.GetFullPath()) != 1 {
+		t.Errorf("message repeats the position: %s", msg)
+	}
+	// The decoder's own error is kept
+	if pe.Err == nil || !strings.Contains(pe.Err.Error(), "Near line") {
+		t.Errorf("ParseError.Err = %v, want the decoder's error", pe.Err)
+	}
+}
@@ -0,0 +1,156 @@
+package userConfig
+
+import (
+	"sort"
+	"strings"
+)
+
+// ConfigFile is a file that a Config reads values from
+// GeneralConfig and AddonConfig both implement it, and other file types can
+// be added to a Config with RegisterFile.
+type ConfigFile interface {
+	// GetName returns the name of the file, without its extension
+	GetName() string
+	// GetPath returns the directory the file is in
+	GetPath() string
+	// GetFullPath returns the full path & filename of the file
+	GetFullPath() string
+	// Load reads the file
+	Load() error
+	// Save writes the file
+	Save() error
+	// GetValue gets the value of k, or an empty string if it isn't set
+	GetValue(k string) string
+	// SetValue sets k to v and saves the file
+	SetValue(k, v string) error
+	// DeleteValue removes k and saves the file
+	DeleteValue(k string) error
+	// Keys returns every key in the file, sorted
+	Keys() []string
+}
+
+var (
+	_ ConfigFile = (*GeneralConfig)(nil)
+	_ ConfigFile = (*AddonConfig)(nil)
+)
+
+// GetValue is Get, for ConfigFile
+func (gf *GeneralConfig) GetValue(k string) string {
+	return gf.Get(k)
+}
+
+// SetValue is Set, for ConfigFile
+func (gf *GeneralConfig) SetValue(k, v string) error {
+	return gf.Set(k, v)
+}
+
+// DeleteValue is DeleteKey, for ConfigFile
+func (gf *GeneralConfig) DeleteValue(k string) error {
+	return gf.DeleteKey(k)
+}
+
+// Keys is GetKeyList, for ConfigFile
+func (gf *GeneralConfig) Keys() []string {
+	return gf.GetKeyList()
+}
+
+// GetValue gets the value of k, given as "<category>.<key>"
+func (af *AddonConfig) GetValue(k string) string {
+	category, key, ok := strings.Cut(k, ".")
+	if !ok {
+		return ""
+	}
+	return af.Get(category, key)
+}
+
+// SetValue sets the value of k, given as "<category>.<key>"
+func (af *AddonConfig) SetValue(k, v string) error {
+	category, key, ok := strings.Cut(k, ".")
+	if !ok {
+		return af.keyError("set", k, "", ErrInvalidName)
+	}
+	return af.Set(category, key, v)
+}
+
+// DeleteValue removes k, given as "<category>.<key>"
+func (af *AddonConfig) DeleteValue(k string) error {
+	category, key, ok := strings.Cut(k, ".")
+	if !ok {
+		return af.keyError("delete", k, "", ErrInvalidName)
+	}
+	return af.DeleteKey(category, key)
+}
+
+// Keys returns every key in af as "<category>.<key>", sorted
+func (af *AddonConfig) Keys() []string {
+	var ret []string
+	for k := range af.FlatValues() {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// RegisterFile loads f and adds it to the config, so GetFile and Files
+// return it along with the general and addon files
+func (c *Config) RegisterFile(f ConfigFile) error {
+	name := f.GetName()
+	if strings.TrimSpace(name) == "" || name == c.general().Name {
+		return &ConfigError{Op: "register", Path: f.GetFullPath(), Err: ErrInvalidName}
+	}
+	for _, n := range c.general().addonNames() {
+		if n == name {
+			return &ConfigError{Op: "register", Path: f.GetFullPath(), Err: ErrInvalidName}
+		}
+	}
+	if err := f.Load(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.files == nil {
+		c.files = make(map[string]ConfigFile)
+	}
+	c.files[name] = f
+	return nil
+}
+
+// GetFile returns the config's file called name: the general file, an
+// addon listed in additional_config or a file added with RegisterFile
+func (c *Config) GetFile(name string) (ConfigFile, error) {
+	if gf := c.general(); name == gf.Name {
+		return gf, nil
+	}
+	c.mu.RLock()
+	f, ok := c.files[name]
+	c.mu.RUnlock()
+	if ok {
+		return f, nil
+	}
+	return c.GetAddonConfig(name)
+}
+
+// Files returns every file in the config: the general file, then each
+// addon in additional_config, then the registered files sorted by name
+func (c *Config) Files() ([]ConfigFile, error) {
+	gf := c.general()
+	ret := []ConfigFile{gf}
+	for _, name := range gf.addonNames() {
+		af, err := c.GetAddonConfig(name)
+		if err != nil {
+			return ret, err
+		}
+		ret = append(ret, af)
+	}
+	c.mu.RLock()
+	var names []string
+	for name := range c.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ret = append(ret, c.files[name])
+	}
+	c.mu.RUnlock()
+	return ret, nil
+}
@@ -0,0 +1,49 @@
+package userConfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem is what a Config reads and writes the files in its config
+// directory with: the general and addon files, included files and drop-ins
+// and raw files, as well as the files a snapshot is made of. Swapping it out
+// is mostly useful in tests, see package userconfigtest.
+// The journal and the snapshot archives are kept in the XDG data directory,
+// which is always on disk, and Watch only notices changes made on disk.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+	Remove(name string) error
+	Glob(pattern string) ([]string, error)
+}
+
+// OSFileSystem is the FileSystem backed by the real disk
+type OSFileSystem struct{}
+
+// ReadFile reads the file name from disk
+func (OSFileSystem) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// WriteFile writes data to the file name on disk
+func (OSFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+
+// MkdirAll creates the directory path and any missing parents on disk
+func (OSFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return verifyOrCreateDirectory(path, perm)
+}
+
+// Remove removes the file name from disk
+func (OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Glob returns the files on disk matching pattern, as filepath.Glob does
+func (OSFileSystem) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
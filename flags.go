@@ -0,0 +1,122 @@
+package userConfig
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/casimir/xdg-go"
+)
+
+// Anything else in an environment variable name is replaced with "_"
+var envNameRegexp = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// FlagBinder fills flags in a flag.FlagSet from a Config, so that a value
+// given on the command line wins over the environment, which wins over
+// the user's config file, then the system config file and finally the
+// flag's default
+type FlagBinder struct {
+	c         *Config
+	fs        *flag.FlagSet
+	keys      map[string]string
+	saveFlags *bool
+}
+
+// BindFlags returns a FlagBinder for fs and registers a --save-flags flag
+// on it, which persists the flags given on the command line to the config
+func (c *Config) BindFlags(fs *flag.FlagSet) *FlagBinder {
+	b := &FlagBinder{c: c, fs: fs, keys: make(map[string]string)}
+	b.saveFlags = fs.Bool("save-flags", false, "save the given flags to the config file")
+	return b
+}
+
+// Bind binds the flag flagName, which has to be defined on the FlagSet, to
+// the config key k
+func (b *FlagBinder) Bind(flagName, k string) *FlagBinder {
+	b.keys[flagName] = k
+	return b
+}
+
+// EnvName returns the environment variable that can set the config key k,
+// <NAME>_<KEY> in upper case
+func (b *FlagBinder) EnvName(k string) string {
+	name := filepath.Base(b.c.name) + "_" + k
+	return envNameRegexp.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+// Apply sets every bound flag that wasn't given on the command line from
+// the environment or config files, then saves the given ones if
+// --save-flags was. Call it after the FlagSet has been parsed.
+func (b *FlagBinder) Apply() error {
+	given := make(map[string]bool)
+	b.fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	// In a fixed order so errors and saves don't change from run to run
+	names := make([]string, 0, len(b.keys))
+	for flagName := range b.keys {
+		names = append(names, flagName)
+	}
+	sort.Strings(names)
+
+	system := b.systemConfig()
+	for _, flagName := range names {
+		k := b.keys[flagName]
+		if given[flagName] {
+			continue
+		}
+		f := b.fs.Lookup(flagName)
+		if f == nil {
+			return &ConfigError{Op: "flag", Key: k, Err: errors.New("flag -" + flagName + " is not defined")}
+		}
+		var v string
+		var ok bool
+		if v, ok = os.LookupEnv(b.EnvName(k)); !ok {
+			if _, ok = b.c.general().lookup(k); ok {
+				v = b.c.Get(k)
+			} else if system != nil {
+				if _, ok = system.lookup(k); ok {
+					v = system.Get(k)
+				}
+			}
+		}
+		if !ok {
+			// Keep the flag's default
+			continue
+		}
+		// Not through fs.Set, which would mark the flag as given
+		if err := f.Value.Set(v); err != nil {
+			return &ConfigError{Op: "flag", Key: k, Err: err}
+		}
+	}
+
+	if !*b.saveFlags {
+		return nil
+	}
+	for _, flagName := range names {
+		if k := b.keys[flagName]; given[flagName] {
+			if err := b.c.Set(k, b.fs.Lookup(flagName).Value.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// systemConfig returns the first system-wide config file for the app in
+// XDG_CONFIG_DIRS, or nil if there isn't one
+func (b *FlagBinder) systemConfig() *GeneralConfig {
+	name := b.c.general().Name
+	for _, dir := range (xdg.App{Name: b.c.name}).SystemConfigPaths("") {
+		if gf, err := newGeneralConfig(name, dir, b.c.naming.GeneralExt, true, OSFileSystem{}); err == nil {
+			gf.interpolate = b.c.interpolate
+			return gf
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package userConfig_test
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestFlagBinderPrecedence(t *testing.T) {
+	system := t.TempDir()
+	t.Setenv("XDG_CONFIG_DIRS", system)
+	writeFiles(t, system, map[string]string{
+		filepath.Join("app", "app.conf"): "[general]\na = \"system\"\nb = \"system\"\nc = \"system\"\nd = \"system\"\n",
+	})
+	c := userconfigtest.New(t, "app")
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.Set(k, "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("APP_A", "env")
+	t.Setenv("APP_B", "env")
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	vals := make(map[string]*string)
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		vals[k] = fs.String(k, "default", "")
+	}
+	b := c.BindFlags(fs)
+	for k := range vals {
+		b.Bind(k, k)
+	}
+	if err := fs.Parse([]string{"-a", "flag", "--save-flags"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range map[string]string{"a": "flag", "b": "env", "c": "user", "d": "system", "e": "default"} {
+		if v := *vals[k]; v != want {
+			t.Errorf("-%s = %q, want %q", k, v, want)
+		}
+	}
+	// Only the flag given on the command line is saved
+	userconfigtest.AssertSaved(t, c, "a", "flag")
+	userconfigtest.AssertSaved(t, c, "b", "user")
+	userconfigtest.AssertSaved(t, c, "c", "user")
+	userconfigtest.AssertNotSaved(t, c, "d")
+	userconfigtest.AssertNotSaved(t, c, "e")
+}
+
+func TestFlagBinderNoSave(t *testing.T) {
+	t.Setenv("XDG_CONFIG_DIRS", t.TempDir())
+	c := userconfigtest.New(t, "app")
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	v := fs.String("a", "default", "")
+	b := c.BindFlags(fs).Bind("a", "a")
+	if err := fs.Parse([]string{"-a", "flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if *v != "flag" {
+		t.Errorf("-a = %q, want flag", *v)
+	}
+	userconfigtest.AssertNotSaved(t, c, "a")
+}
@@ -0,0 +1,200 @@
+package userConfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultHistoryLimit is how many changes are kept in a config's journal
+const DefaultHistoryLimit = 100
+
+// historyFile is the name of the journal in the XDG data directory
+const historyFile = "history.json"
+
+// Change is one entry in a config's change journal
+type Change struct {
+	Time time.Time `json:"time"`
+	Key  string    `json:"key"`
+	Old  string    `json:"old"`
+	New  string    `json:"new"`
+	// Existed is whether the key had a value before the change
+	Existed bool `json:"existed"`
+	// Deleted is whether the change removed the key
+	Deleted bool   `json:"deleted"`
+	Process string `json:"process"`
+
+	// released is the raw file the change took off the raw_files list
+	released string
+}
+
+// History returns the journaled changes to k, oldest first
+// An empty k returns the changes to every key.
+func (c *Config) History(k string) ([]Change, error) {
+	all, err := c.readHistory()
+	if err != nil || k == "" {
+		return all, err
+	}
+	var ret []Change
+	for _, ch := range all {
+		if ch.Key == k {
+			ret = append(ret, ch)
+		}
+	}
+	return ret, nil
+}
+
+// Undo reverts the last n journaled changes, newest first, and removes them
+// from the journal
+func (c *Config) Undo(n int) error {
+	if n < 0 {
+		return &ConfigError{Op: "undo", Path: c.name, Err: fmt.Errorf("can't undo %d changes", n)}
+	}
+	if c.readOnly {
+		return &ConfigError{Op: "undo", Path: c.name, Err: ErrReadOnly}
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	all, err := c.readHistory()
+	if err != nil {
+		return err
+	}
+	if n > len(all) {
+		n = len(all)
+	}
+	return c.revert(all, len(all)-n)
+}
+
+// RestoreAt reverts every journaled change made after t
+func (c *Config) RestoreAt(t time.Time) error {
+	if c.readOnly {
+		return &ConfigError{Op: "restore", Path: c.name, Err: ErrReadOnly}
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	all, err := c.readHistory()
+	if err != nil {
+		return err
+	}
+	keep := len(all)
+	for keep > 0 && all[keep-1].Time.After(t) {
+		keep--
+	}
+	return c.revert(all, keep)
+}
+
+// revert undoes all[keep:] and writes all[:keep] back as the journal
+// c.writeMu has to be held, from reading the journal on.
+func (c *Config) revert(all []Change, keep int) error {
+	if c.general() == nil {
+		return &ConfigError{Op: "undo", Path: c.name, Err: ErrNotLoaded}
+	}
+	// Undoing shouldn't add to the journal
+	c.replaying = true
+	defer func() { c.replaying = false }()
+	for i := len(all) - 1; i >= keep; i-- {
+		var err error
+		if all[i].Existed {
+			err = c.general().restore(all[i].Key, all[i].Old)
+		} else {
+			err = c.general().DeleteKey(all[i].Key)
+		}
+		if err != nil {
+			// Keep what we didn't manage to undo
+			if werr := c.writeHistory(all[:i+1]); werr != nil {
+				return fmt.Errorf("%w; %w", err, werr)
+			}
+			c.releaseBlobs(all[i+1:])
+			return err
+		}
+	}
+	if err := c.writeHistory(all[:keep]); err != nil {
+		return err
+	}
+	c.releaseBlobs(all[keep:])
+	return nil
+}
+
+// recordChange adds ch to the journal and returns the changes that were
+// dropped from it to stay within the limit
+// The journal is best-effort, a failure to write it doesn't undo the change.
+func (c *Config) recordChange(ch Change) []Change {
+	if c.replaying || c.historyLimit < 0 {
+		return nil
+	}
+	ch.Process = filepath.Base(os.Args[0]) + "[" + strconv.Itoa(os.Getpid()) + "]"
+	all, err := c.readHistory()
+	if err != nil {
+		return nil
+	}
+	all = append(all, ch)
+	limit := c.historyLimit
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	var dropped []Change
+	if len(all) > limit {
+		dropped = all[:len(all)-limit]
+		all = all[len(all)-limit:]
+	}
+	if c.writeHistory(all) != nil {
+		return nil
+	}
+	return dropped
+}
+
+// releaseBlobs removes the raw files that changes released or pointed to,
+// once neither the config nor the journal uses them
+func (c *Config) releaseBlobs(changes []Change) {
+	var names []string
+	for _, ch := range changes {
+		for _, name := range []string{ch.released, blobOf(ch.Old), blobOf(ch.New)} {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	journal, err := c.readHistory()
+	if err != nil {
+		// Better to leave a file behind than to lose one the journal needs
+		return
+	}
+	c.general().removeBlobs(names, journal)
+}
+
+// readHistory reads the whole journal from the data directory
+func (c *Config) readHistory() ([]Change, error) {
+	var ret []Change
+	if c.dataDir == nil {
+		return ret, nil
+	}
+	data, err := c.dataDir.ReadFile(historyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ret, nil
+		}
+		return ret, &ConfigError{Op: "history", Path: c.dataDir.GetFullPath(historyFile), Err: err}
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return ret, &ConfigError{Op: "history", Path: c.dataDir.GetFullPath(historyFile), Err: err}
+	}
+	return ret, nil
+}
+
+// writeHistory replaces the journal in the data directory with all
+func (c *Config) writeHistory(all []Change) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = c.dataDir.WriteFile(historyFile, data); err != nil {
+		return &ConfigError{Op: "history", Path: c.dataDir.GetFullPath(historyFile), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package userConfig_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestHistory(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	for _, v := range []string{"1", "2"} {
+		if err := c.Set("a", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Set("b", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := c.History("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("%d changes in the journal, want 3", len(all))
+	}
+	a, err := c.History("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 2 || a[0].Existed || a[1].Old != "1" || a[1].New != "2" || !a[1].Existed {
+		t.Errorf("changes to a = %+v, want a new key then 1 to 2", a)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	if err := c.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("a", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("b", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteKey("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The delete, then the new key
+	if err := c.Undo(2); err != nil {
+		t.Fatal(err)
+	}
+	userconfigtest.AssertSaved(t, c, "a", "2")
+	userconfigtest.AssertNotSaved(t, c, "b")
+	if err := c.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	userconfigtest.AssertSaved(t, c, "a", "1")
+
+	all, err := c.History("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("%d changes left in the journal, want 1", len(all))
+	}
+	// More than there are undoes the lot
+	if err := c.Undo(10); err != nil {
+		t.Fatal(err)
+	}
+	userconfigtest.AssertNotSaved(t, c, "a")
+}
+
+func TestRestoreAt(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	if err := c.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	all, err := c.History("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := all[len(all)-1].Time
+	time.Sleep(10 * time.Millisecond)
+	if err := c.Set("a", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("b", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.RestoreAt(at); err != nil {
+		t.Fatal(err)
+	}
+	userconfigtest.AssertSaved(t, c, "a", "1")
+	userconfigtest.AssertNotSaved(t, c, "b")
+	if all, _ = c.History(""); len(all) != 1 {
+		t.Errorf("%d changes left in the journal, want 1", len(all))
+	}
+}
+
+func TestUndoBlob(t *testing.T) {
+	c := userconfigtest.New(t, "app", userConfig.WithBlobLimit(4), userConfig.WithHistoryLimit(2))
+	blobs := func() []string {
+		matches, _ := filepath.Glob(filepath.Join(c.GetConfigPath(), "*.bin"))
+		return matches
+	}
+	big := []byte("too big to inline")
+	if err := c.SetBytes("big", big); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetBytes("big", []byte("tiny")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(blobs()); n != 1 {
+		t.Errorf("%d raw files while the journal points to one, want 1", n)
+	}
+
+	if err := c.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetBytes("big"); !bytes.Equal(got, big) {
+		t.Errorf("big = %q after undoing, want %q", got, big)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetBytes("big"); !bytes.Equal(got, big) {
+		t.Errorf("big = %q after reloading, want %q", got, big)
+	}
+
+	// Once the journal has moved on, the file goes
+	for _, v := range []string{"a", "b", "c"} {
+		if err := c.SetBytes("big", []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(blobs()); n != 0 {
+		t.Errorf("%d raw files after the journal dropped them, want 0", n)
+	}
+}
@@ -0,0 +1,389 @@
+package userConfig
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultSecretPatterns match the keys whose values HTTPHandler masks when
+// listing a config, compared in lower case
+var DefaultSecretPatterns = []string{"*password*", "*secret*", "*token*", "*api_key*", "*private_key*"}
+
+// maskedValue is shown in place of a secret value
+const maskedValue = "********"
+
+// maxValueSize is the largest request body HTTPHandler accepts for a value
+const maxValueSize = 1 << 20
+
+// HTTPHandler serves a Config over HTTP:
+//
+//	GET /config                                       every key as JSON, secrets masked
+//	GET, PUT, DELETE /config/{key}                    a single key
+//	GET /config/addons/{name}[/{category}]            an addon config as JSON, secrets masked
+//	GET, PUT, DELETE /config/addons/{name}/{category}/{key}
+//
+// PUT takes the value as the request body, or as {"value": ...} when sent
+// as application/json. Every single value has an ETag, and PUT and DELETE
+// honor If-Match (and If-None-Match: * on PUT) so concurrent edits don't
+// overwrite each other.
+type HTTPHandler struct {
+	Config *Config
+	// Prefix is the path the handler is mounted at, "/config" by default
+	Prefix string
+	// Authorize is called before every request, write is whether the
+	// request changes the config. Returning an error rejects it with 403.
+	Authorize func(r *http.Request, write bool) error
+	// IsSecret reports whether the value of a key should be masked when
+	// listing, it defaults to matching DefaultSecretPatterns
+	IsSecret func(k string) bool
+}
+
+// NewHTTPHandler returns an HTTPHandler for c mounted at /config
+func NewHTTPHandler(c *Config) *HTTPHandler {
+	return &HTTPHandler{Config: c, Prefix: "/config"}
+}
+
+// keyValue is how a single value is sent and received as JSON
+type keyValue struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// ServeHTTP routes the request to the general or addon config
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimSuffix(h.Prefix, "/")
+	if prefix == "" {
+		prefix = "/config"
+	}
+	rest := r.URL.EscapedPath()
+	if rest != prefix && !strings.HasPrefix(rest, prefix+"/") {
+		http.NotFound(w, r)
+		return
+	}
+	var parts []string
+	for _, p := range strings.Split(strings.Trim(strings.TrimPrefix(rest, prefix), "/"), "/") {
+		if p == "" {
+			continue
+		}
+		unescaped, err := url.PathUnescape(p)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		parts = append(parts, unescaped)
+	}
+
+	write := r.Method == http.MethodPut || r.Method == http.MethodDelete
+	if !write && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Authorize != nil {
+		if err := h.Authorize(r, write); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	if write && h.Config.IsReadOnly() {
+		http.Error(w, ErrReadOnly.Error(), http.StatusConflict)
+		return
+	}
+
+	switch {
+	case len(parts) == 0 && !write:
+		h.serveValues(w, r, h.Config.general().effectiveValues())
+	case len(parts) == 1:
+		h.serveGeneralKey(w, r, parts[0])
+	case parts[0] == "addons" && len(parts) > 1:
+		h.serveAddon(w, r, parts[1:])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveGeneralKey handles a single key in the general config
+func (h *HTTPHandler) serveGeneralKey(w http.ResponseWriter, r *http.Request, k string) {
+	c := h.Config
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		cur, ok := c.general().lookup(k)
+		h.serveGet(w, r, k, cur, ok)
+	case http.MethodPut:
+		v, err := readValue(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The preconditions are checked and the value set without letting
+		// go of the config, so two PUTs with the same If-Match can't both
+		// succeed
+		var status int
+		err = c.update(func(gf *GeneralConfig) error {
+			cur, ok := gf.lookup(k)
+			if status = preconditionStatus(r, cur, ok); status != 0 {
+				return nil
+			}
+			return gf.Set(k, v)
+		})
+		h.servePut(w, status, err, k, v)
+	case http.MethodDelete:
+		var status int
+		err := c.update(func(gf *GeneralConfig) error {
+			cur, ok := gf.lookup(k)
+			if status = deleteStatus(r, cur, ok); status != 0 {
+				return nil
+			}
+			return gf.DeleteKey(k)
+		})
+		h.serveDelete(w, status, err)
+	}
+}
+
+// serveAddon handles the addons/{name}/{category}/{key} tree
+func (h *HTTPHandler) serveAddon(w http.ResponseWriter, r *http.Request, parts []string) {
+	af, err := h.Config.GetAddonConfig(parts[0])
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	switch len(parts) {
+	case 1, 2:
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		vals := af.FlatValues()
+		if len(parts) == 2 {
+			// Just the one category
+			prefix := parts[1] + "."
+			for k, v := range vals {
+				delete(vals, k)
+				if strings.HasPrefix(k, prefix) {
+					vals[strings.TrimPrefix(k, prefix)] = v
+				}
+			}
+		}
+		h.serveValues(w, r, vals)
+		return
+	case 3:
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	category, k := parts[1], parts[2]
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		cur, ok := af.lookup(category, k)
+		h.serveGet(w, r, k, cur, ok)
+	case http.MethodPut:
+		v, err := readValue(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		af.mu.Lock()
+		cur, ok := af.lookupLocked(category, k)
+		status := preconditionStatus(r, cur, ok)
+		if status == 0 {
+			err = af.set(category, k, v)
+		}
+		af.mu.Unlock()
+		h.servePut(w, status, err, k, v)
+	case http.MethodDelete:
+		af.mu.Lock()
+		cur, ok := af.lookupLocked(category, k)
+		status := deleteStatus(r, cur, ok)
+		if status == 0 {
+			err = af.deleteKey(category, k)
+		}
+		af.mu.Unlock()
+		h.serveDelete(w, status, err)
+	}
+}
+
+// serveGet answers a GET or HEAD for a single value
+func (h *HTTPHandler) serveGet(w http.ResponseWriter, r *http.Request, k, cur string, exists bool) {
+	if status := preconditionStatus(r, cur, exists); status != 0 {
+		writeStatus(w, status)
+		return
+	}
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	h.serveValue(w, k, cur)
+}
+
+// servePut answers a PUT, status is what the preconditions came to and err
+// what setting the value did
+func (h *HTTPHandler) servePut(w http.ResponseWriter, status int, err error, k, v string) {
+	switch {
+	case status != 0:
+		writeStatus(w, status)
+	case err != nil:
+		httpError(w, err)
+	default:
+		h.serveValue(w, k, v)
+	}
+}
+
+// serveDelete answers a DELETE, like servePut
+func (h *HTTPHandler) serveDelete(w http.ResponseWriter, status int, err error) {
+	switch {
+	case status != 0:
+		writeStatus(w, status)
+	case err != nil:
+		httpError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// serveValues writes vals as a JSON object with secrets masked
+func (h *HTTPHandler) serveValues(w http.ResponseWriter, r *http.Request, vals map[string]string) {
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sum := sha1.New()
+	for _, k := range keys {
+		sum.Write([]byte(k + "\x00" + vals[k] + "\x00"))
+		if h.isSecret(k) {
+			vals[k] = maskedValue
+		}
+	}
+	etag := `"` + hex.EncodeToString(sum.Sum(nil))[:16] + `"`
+	if match := r.Header.Get("If-None-Match"); match != "" && match == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	writeJSON(w, vals)
+}
+
+// serveValue writes a single value along with its ETag
+func (h *HTTPHandler) serveValue(w http.ResponseWriter, k, v string) {
+	w.Header().Set("ETag", valueETag(v))
+	writeJSON(w, keyValue{Key: k, Value: v})
+}
+
+// preconditionStatus checks If-Match and If-None-Match for a single value,
+// returning 412 (or 304 for GET and HEAD) if they don't hold and 0 if they
+// do
+func preconditionStatus(r *http.Request, cur string, exists bool) int {
+	etag := valueETag(cur)
+	if match := r.Header.Get("If-Match"); match != "" {
+		if !exists || (match != "*" && !etagListContains(match, etag)) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" && exists {
+		if match == "*" || etagListContains(match, etag) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	}
+	return 0
+}
+
+// deleteStatus is preconditionStatus for a DELETE, which also needs the
+// value to exist
+func deleteStatus(r *http.Request, cur string, exists bool) int {
+	if status := preconditionStatus(r, cur, exists); status != 0 {
+		return status
+	}
+	if !exists {
+		return http.StatusNotFound
+	}
+	return 0
+}
+
+// writeStatus writes a response with just status
+func writeStatus(w http.ResponseWriter, status int) {
+	switch status {
+	case http.StatusNotModified:
+		w.WriteHeader(status)
+	case http.StatusPreconditionFailed:
+		http.Error(w, "precondition failed", status)
+	default:
+		http.Error(w, http.StatusText(status), status)
+	}
+}
+
+// isSecret reports whether k's value should be masked
+func (h *HTTPHandler) isSecret(k string) bool {
+	if h.IsSecret != nil {
+		return h.IsSecret(k)
+	}
+	for _, p := range DefaultSecretPatterns {
+		if keyMatches(p, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readValue reads the value from a PUT request body
+func readValue(w http.ResponseWriter, r *http.Request) (string, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+	if err != nil {
+		return "", err
+	}
+	if ct := r.Header.Get("Content-Type"); strings.HasPrefix(ct, "application/json") {
+		var kv keyValue
+		if err = json.Unmarshal(body, &kv); err != nil {
+			return "", err
+		}
+		return kv.Value, nil
+	}
+	return string(body), nil
+}
+
+// valueETag returns the ETag for a single value
+func valueETag(v string) string {
+	sum := sha1.Sum([]byte(v))
+	return `"` + hex.EncodeToString(sum[:])[:16] + `"`
+}
+
+// etagListContains reports whether a comma separated If-Match style list
+// has etag in it
+func etagListContains(list, etag string) bool {
+	for _, e := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(e), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// httpError writes err with the status that best fits it
+func httpError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotExist), errors.Is(err, ErrNoKey):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrReadOnly):
+		status = http.StatusConflict
+	case errors.Is(err, ErrPermission):
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// writeJSON writes v as the JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
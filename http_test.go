@@ -0,0 +1,84 @@
+package userConfig_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func put(h http.Handler, path, value, ifMatch string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPut, path, strings.NewReader(value))
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHTTPConcurrentPuts(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	h := userConfig.NewHTTPHandler(c)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				k := "/config/k" + strconv.Itoa(i)
+				if w := put(h, k, strconv.Itoa(j), ""); w.Code != http.StatusOK {
+					t.Errorf("PUT %s: %d %s", k, w.Code, w.Body)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 8; i++ {
+		userconfigtest.AssertSaved(t, c, "k"+strconv.Itoa(i), "9")
+	}
+}
+
+func TestHTTPIfMatch(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	h := userConfig.NewHTTPHandler(c)
+	w := put(h, "/config/name", "first", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("PUT: %d, ETag %q", w.Code, etag)
+	}
+
+	// Both PUTs are based on the same value, only one may win
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = put(h, "/config/name", "second"+strconv.Itoa(i), etag).Code
+		}(i)
+	}
+	wg.Wait()
+	ok := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			ok++
+		case http.StatusPreconditionFailed:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if ok != 1 {
+		t.Errorf("%d PUTs succeeded with the same If-Match, want 1", ok)
+	}
+
+	if w = put(h, "/config/name", "third", etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with a stale ETag: %d, want 412", w.Code)
+	}
+}
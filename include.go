@@ -0,0 +1,146 @@
+package userConfig
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrIncludeCycle is returned when config files include each other
+var ErrIncludeCycle = errors.New("include cycle")
+
+// configLayer is the values from one included or drop-in file
+type configLayer struct {
+	path   string
+	values map[string]string
+}
+
+// configFragment is the part of an included or drop-in file that we read
+type configFragment struct {
+	Include []string               `toml:"include"`
+	Values  map[string]interface{} `toml:"general"`
+}
+
+// GetDropInDir returns the directory that drop-in files for gf are read
+// from, <name>.conf.d with the default NamingPolicy
+func (gf *GeneralConfig) GetDropInDir() string {
+	return filepath.Join(gf.Path, gf.Name+gf.extension()+".d")
+}
+
+// Source returns the file that the value for k comes from, or an empty
+// string if k isn't set anywhere
+func (gf *GeneralConfig) Source(k string) string {
+	gf.mu.RLock()
+	defer gf.mu.RUnlock()
+	for _, l := range gf.overlays {
+		if _, ok := l.values[k]; ok {
+			return l.path
+		}
+	}
+	if _, ok := gf.Values[k]; ok {
+		return gf.GetFullPath()
+	}
+	for _, l := range gf.layers {
+		if _, ok := l.values[k]; ok {
+			return l.path
+		}
+	}
+	return ""
+}
+
+// lookup finds the unexpanded value for k, first in the shared configs that
+// win over the main file, then the main file and then the layers below it
+func (gf *GeneralConfig) lookup(k string) (string, bool) {
+	gf.mu.RLock()
+	defer gf.mu.RUnlock()
+	return gf.lookupLocked(k)
+}
+
+// lookupLocked is lookup for callers that hold gf.mu
+func (gf *GeneralConfig) lookupLocked(k string) (string, bool) {
+	for _, l := range gf.overlays {
+		if v, ok := l.values[k]; ok {
+			return v, true
+		}
+	}
+	if v, ok := gf.Values[k]; ok {
+		return v, true
+	}
+	for _, l := range gf.layers {
+		if v, ok := l.values[k]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// readLayers reads the files in include and then every file in the drop-in
+// directory with gf's extension in lexical order, and returns them with the
+// last applied file first. Later files win over earlier ones, and the
+// main file wins over all of them.
+func (gf *GeneralConfig) readLayers(include []string) ([]configLayer, error) {
+	var applied []configLayer
+	seen := make(map[string]bool)
+	stack := []string{gf.GetFullPath()}
+	if err := gf.includeFiles(gf.Path, include, stack, seen, &applied); err != nil {
+		return nil, err
+	}
+	dropIns, err := gf.fileSystem().Glob(filepath.Join(gf.GetDropInDir(), "*"+gf.extension()))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dropIns)
+	if err = gf.includeFiles(gf.Path, dropIns, stack, seen, &applied); err != nil {
+		return nil, err
+	}
+
+	// Look things up from the last applied file down
+	var layers []configLayer
+	for i := len(applied) - 1; i >= 0; i-- {
+		layers = append(layers, applied[i])
+	}
+	return layers, nil
+}
+
+// includeFiles applies every file matching patterns (relative to dir),
+// along with the files that they include
+func (gf *GeneralConfig) includeFiles(dir string, patterns, stack []string, seen map[string]bool, applied *[]configLayer) error {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := gf.fileSystem().Glob(pattern)
+		if err != nil {
+			return &ConfigError{Op: "include", Path: pattern, Err: err}
+		}
+		sort.Strings(matches)
+		for _, path := range matches {
+			for _, s := range stack {
+				if s == path {
+					return &ConfigError{Op: "include", Path: path, Err: fmt.Errorf("%s: %w", strings.Join(append(stack, path), " -> "), ErrIncludeCycle)}
+				}
+			}
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+
+			data, err := gf.fileSystem().ReadFile(path)
+			if err != nil {
+				return &ConfigError{Op: "include", Path: path, Err: err}
+			}
+			var frag configFragment
+			if _, err = decodeTOML(string(data), &frag); err != nil {
+				return &ConfigError{Op: "include", Path: path, Err: newParseError(path, err)}
+			}
+			// What this file includes goes below it
+			if err = gf.includeFiles(filepath.Dir(path), frag.Include, append(stack, path), seen, applied); err != nil {
+				return err
+			}
+			*applied = append(*applied, configLayer{path: path, values: tomlValues(frag.Values)})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package userConfig_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestIncludes(t *testing.T) {
+	c := userconfigtest.FromTOML(t, "app", "include = [\"base.conf\", \"parts/*.conf\"]\n[general]\nmain = \"main\"\n")
+	dir := c.GetConfigPath()
+	writeFiles(t, dir, map[string]string{
+		"base.conf":              "[general]\nbase = \"base\"\nmain = \"base\"\npart = \"base\"\n",
+		"parts/a.conf":           "[general]\npart = \"a\"\n",
+		"parts/b.conf":           "[general]\npart = \"b\"\nb = \"b\"\n",
+		"app.conf.d/10-one.conf": "[general]\ndrop = \"one\"\nmain = \"one\"\n",
+		"app.conf.d/20-two.conf": "[general]\ndrop = \"two\"\npart = \"two\"\n",
+		"app.conf.d/ignored.txt": "[general]\ndrop = \"ignored\"\n",
+	})
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range map[string]struct{ value, source string }{
+		// The main file wins over everything it includes
+		"main": {"main", "app.conf"},
+		"base": {"base", "base.conf"},
+		// Later globbed files win, and drop-ins win over includes
+		"b":    {"b", "parts/b.conf"},
+		"part": {"two", "app.conf.d/20-two.conf"},
+		"drop": {"two", "app.conf.d/20-two.conf"},
+	} {
+		if v := c.Get(k); v != want.value {
+			t.Errorf("%s = %q, want %q", k, v, want.value)
+		}
+		if src := c.Source(k); src != filepath.Join(dir, filepath.FromSlash(want.source)) {
+			t.Errorf("source of %s = %s, want %s", k, src, want.source)
+		}
+	}
+	if src := c.Source("nope"); src != "" {
+		t.Errorf("source of a missing key = %q, want none", src)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	c := userconfigtest.FromTOML(t, "app", "[general]\n")
+	writeFiles(t, c.GetConfigPath(), map[string]string{
+		"app.conf": "include = [\"a.conf\"]\n[general]\n",
+		"a.conf":   "include = [\"b.conf\"]\n",
+		"b.conf":   "include = [\"a.conf\"]\n",
+	})
+	if err := c.Reload(); !errors.Is(err, userConfig.ErrIncludeCycle) {
+		t.Errorf("Reload with an include cycle returned %v, want ErrIncludeCycle", err)
+	}
+}
@@ -0,0 +1,121 @@
+package userConfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrInterpolationCycle is returned when a value refers back to itself
+	ErrInterpolationCycle = errors.New("reference cycle")
+	// ErrUndefinedReference is returned when a value refers to something
+	// that doesn't exist
+	ErrUndefinedReference = errors.New("undefined reference")
+)
+
+// GetRaw gets a key/value pair from gf without expanding any references
+func (gf *GeneralConfig) GetRaw(k string) string {
+	v, _ := gf.lookup(k)
+	return v
+}
+
+// GetExpanded gets a key/value pair from gf with any ${...} references
+// expanded, or an error if one of them can't be
+// A literal "${" is written as "$${".
+func (gf *GeneralConfig) GetExpanded(k string) (string, error) {
+	v, err := gf.expand(gf.GetRaw(k), []string{k})
+	if err != nil {
+		return v, gf.keyError("expand", k, err)
+	}
+	return v, nil
+}
+
+// expand replaces the references in v, stack is the chain of references
+// that led to v
+func (gf *GeneralConfig) expand(v string, stack []string) (string, error) {
+	var ret strings.Builder
+	for {
+		i := strings.Index(v, "${")
+		if i < 0 {
+			ret.WriteString(v)
+			return ret.String(), nil
+		}
+		if i > 0 && v[i-1] == '$' {
+			// Escaped, keep a single "${"
+			ret.WriteString(v[:i])
+			ret.WriteString("{")
+			v = v[i+2:]
+			continue
+		}
+		end := strings.Index(v[i:], "}")
+		if end < 0 {
+			return ret.String(), errors.New("unterminated reference in " + v)
+		}
+		ref := v[i+2 : i+end]
+		for _, s := range stack {
+			if s == ref {
+				return ret.String(), fmt.Errorf("%s: %w", strings.Join(append(stack, ref), " -> "), ErrInterpolationCycle)
+			}
+		}
+		raw, err := gf.lookupRef(ref)
+		if err != nil {
+			return ret.String(), err
+		}
+		expanded, err := gf.expand(raw, append(stack, ref))
+		if err != nil {
+			return ret.String(), err
+		}
+		ret.WriteString(v[:i])
+		ret.WriteString(expanded)
+		v = v[i+end+1:]
+	}
+}
+
+// lookupRef returns the unexpanded value of a reference, either another key
+// in gf or a "<scheme>:<name>" handled by the resolver
+func (gf *GeneralConfig) lookupRef(ref string) (string, error) {
+	if i := strings.Index(ref, ":"); i >= 0 {
+		scheme, name := ref[:i], ref[i+1:]
+		if scheme == "env" {
+			return os.Getenv(name), nil
+		}
+		if gf.resolver != nil {
+			return gf.resolver(scheme, name)
+		}
+		return "", fmt.Errorf("%s: %w", ref, ErrUndefinedReference)
+	}
+	if v, ok := gf.lookup(ref); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("%s: %w", ref, ErrUndefinedReference)
+}
+
+// resolveRef resolves the xdg: and addon: references for the general config
+func (c *Config) resolveRef(scheme, name string) (string, error) {
+	switch scheme {
+	case "xdg":
+		switch name {
+		case "config":
+			return c.GetConfigPath(), nil
+		case "data":
+			return c.dataDir.Path, nil
+		case "cache":
+			return c.cacheDir.Path, nil
+		}
+	case "addon":
+		// name.category.key
+		parts := strings.SplitN(name, ".", 3)
+		if len(parts) == 3 {
+			af, err := c.GetAddonConfig(parts[0])
+			if err != nil {
+				return "", err
+			}
+			if v, ok := af.lookup(parts[1], parts[2]); ok {
+				return v, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s:%s: %w", scheme, name, ErrUndefinedReference)
+}
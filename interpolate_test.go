@@ -0,0 +1,40 @@
+package userConfig_test
+
+import (
+	"errors"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestInterpolation(t *testing.T) {
+	c := userconfigtest.FromTOML(t, "app", `[general]
+host = "example.com"
+url = "https://${host}/api"
+literal = "cost: $${price}"
+mixed = "$${host} is ${host}"
+loop_a = "${loop_b}"
+loop_b = "${loop_a}"
+missing = "${nope}"
+`, userConfig.WithInterpolation())
+
+	for k, want := range map[string]string{
+		"url":     "https://example.com/api",
+		"literal": "cost: ${price}",
+		"mixed":   "${host} is example.com",
+	} {
+		if v := c.Get(k); v != want {
+			t.Errorf("%s = %q, want %q", k, v, want)
+		}
+	}
+	if v := c.GetRaw("literal"); v != "cost: $${price}" {
+		t.Errorf("raw literal = %q, want it unexpanded", v)
+	}
+	if _, err := c.GetExpanded("loop_a"); !errors.Is(err, userConfig.ErrInterpolationCycle) {
+		t.Errorf("expanding a cycle returned %v, want ErrInterpolationCycle", err)
+	}
+	if _, err := c.GetExpanded("missing"); !errors.Is(err, userConfig.ErrUndefinedReference) {
+		t.Errorf("expanding a missing key returned %v, want ErrUndefinedReference", err)
+	}
+}
@@ -0,0 +1,165 @@
+package userConfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NamingPolicy is how a config's files are named on disk
+type NamingPolicy struct {
+	// GeneralExt is the extension of the general config file and of the
+	// drop-in files in its <name><ext>.d directory
+	GeneralExt string
+	// AddonExt is the extension of the addon config files
+	AddonExt string
+}
+
+// DefaultNaming names the general file <name>.conf and addons <name>.toml
+var DefaultNaming = NamingPolicy{GeneralExt: ".conf", AddonExt: ".toml"}
+
+// reservedChars can't be used in a file name on at least one platform
+const reservedChars = `/\<>:"|?*`
+
+// ValidateFileName returns an error matching ErrInvalidName if name can't
+// be used as the name of a config file: it's empty, "." or "..", has a
+// path separator or another reserved or control character in it, or
+// starts or ends with a space or ends with a dot
+func ValidateFileName(name string) error {
+	bad := name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, reservedChars) ||
+		strings.TrimSpace(name) != name || strings.HasSuffix(name, ".")
+	for _, r := range name {
+		bad = bad || r < 0x20 || r == 0x7f
+	}
+	if bad {
+		return fmt.Errorf("%q: %w", name, ErrInvalidName)
+	}
+	return nil
+}
+
+// withDefaults fills in the extensions p leaves empty from DefaultNaming
+func (p NamingPolicy) withDefaults() NamingPolicy {
+	if p.GeneralExt == "" {
+		p.GeneralExt = DefaultNaming.GeneralExt
+	}
+	if p.AddonExt == "" {
+		p.AddonExt = DefaultNaming.AddonExt
+	}
+	return p
+}
+
+// validate checks that both extensions start with a dot and are valid in a
+// file name
+func (p NamingPolicy) validate() error {
+	for _, ext := range []string{p.GeneralExt, p.AddonExt} {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("extension %q: %w", ext, ErrInvalidName)
+		}
+		if err := ValidateFileName("x" + ext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileRename is a file that MigrateFileNames renamed
+type FileRename struct {
+	From string
+	To   string
+}
+
+// MigrateFileNames renames the files of the config called name in dir that
+// were written under older conventions to the ones in policy: the general
+// file and drop-ins with the default extension, and addons that were
+// listed in additional_config with their extension (which got it twice).
+// A file is left alone if the new name is already taken.
+func MigrateFileNames(dir, name string, policy NamingPolicy) ([]FileRename, error) {
+	policy = policy.withDefaults()
+	if err := policy.validate(); err != nil {
+		return nil, &ConfigError{Op: "migrate", Path: dir, Err: err}
+	}
+	var renames []FileRename
+	rename := func(from, to string) error {
+		if from == to {
+			return nil
+		}
+		if _, err := os.Stat(from); err != nil {
+			return nil
+		}
+		if _, err := os.Stat(to); err == nil {
+			return nil
+		}
+		if err := os.Rename(from, to); err != nil {
+			return &ConfigError{Op: "migrate", Path: from, Err: err}
+		}
+		renames = append(renames, FileRename{From: from, To: to})
+		return nil
+	}
+
+	// The general file and its drop-ins
+	legacyExt := DefaultNaming.GeneralExt
+	if err := rename(filepath.Join(dir, name+legacyExt), filepath.Join(dir, name+policy.GeneralExt)); err != nil {
+		return renames, err
+	}
+	dropInDir := filepath.Join(dir, name+policy.GeneralExt+".d")
+	if err := rename(filepath.Join(dir, name+legacyExt+".d"), dropInDir); err != nil {
+		return renames, err
+	}
+	if legacyExt != policy.GeneralExt {
+		dropIns, _ := filepath.Glob(filepath.Join(dropInDir, "*"+legacyExt))
+		for _, p := range dropIns {
+			if err := rename(p, strings.TrimSuffix(p, legacyExt)+policy.GeneralExt); err != nil {
+				return renames, err
+			}
+		}
+	}
+
+	// Addons, which are listed in the general file
+	cfgPath := filepath.Join(dir, name+policy.GeneralExt)
+	data, err := ioutil.ReadFile(cfgPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return renames, nil
+		}
+		return renames, &ConfigError{Op: "migrate", Path: cfgPath, Err: err}
+	}
+	var file generalFile
+	if _, err = decodeTOML(string(data), &file); err != nil {
+		return renames, &ConfigError{Op: "migrate", Path: cfgPath, Err: newParseError(cfgPath, err)}
+	}
+	relisted := false
+	for i, addon := range file.ConfigFiles {
+		base := addon
+		for _, ext := range []string{DefaultNaming.AddonExt, policy.AddonExt} {
+			base = strings.TrimSuffix(base, ext)
+		}
+		if ValidateFileName(addon) != nil || ValidateFileName(base) != nil {
+			// Not something Config would open, and it may point outside dir
+			continue
+		}
+		to := filepath.Join(dir, base+policy.AddonExt)
+		for _, from := range []string{addon + DefaultNaming.AddonExt, addon + policy.AddonExt, base + DefaultNaming.AddonExt} {
+			if err = rename(filepath.Join(dir, from), to); err != nil {
+				return renames, err
+			}
+		}
+		if base != addon {
+			file.ConfigFiles[i] = base
+			relisted = true
+		}
+	}
+	if relisted {
+		gf, err := newGeneralConfig(name, dir, policy.GeneralExt, false, OSFileSystem{})
+		if err != nil {
+			return renames, err
+		}
+		gf.ConfigFiles = file.ConfigFiles
+		if err = gf.Save(); err != nil {
+			return renames, err
+		}
+	}
+	return renames, nil
+}
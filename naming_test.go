@@ -0,0 +1,108 @@
+package userConfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+// writeFiles writes each file in files, relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertFiles checks which of names exist in dir
+func assertFiles(t *testing.T, dir string, want map[string]bool) {
+	t.Helper()
+	for name, exists := range want {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists && err != nil {
+			t.Errorf("%s is missing: %v", name, err)
+		} else if !exists && err == nil {
+			t.Errorf("%s is still there", name)
+		}
+	}
+}
+
+func TestMigrateDoubleExtension(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b")
+	writeFiles(t, dir, map[string]string{
+		"app.conf":        "additional_config = [\"extra.toml\", \"../../evil.toml\"]\n[general]\n",
+		"extra.toml.toml": "[a]\nk = \"1\"\n",
+	})
+	writeFiles(t, root, map[string]string{"evil.toml.toml": ""})
+
+	renames, err := userConfig.MigrateFileNames(dir, "app", userConfig.DefaultNaming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renames) != 1 {
+		t.Errorf("renames = %+v, want just the addon", renames)
+	}
+	assertFiles(t, dir, map[string]bool{"extra.toml": true, "extra.toml.toml": false})
+	assertFiles(t, root, map[string]bool{"evil.toml.toml": true, "evil.toml": false})
+	data, err := os.ReadFile(filepath.Join(dir, "app.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"extra"`) || strings.Contains(string(data), `"extra.toml"`) {
+		t.Errorf("the addon wasn't relisted without its extension:\n%s", data)
+	}
+}
+
+func TestMigrateGeneralExt(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.conf":          "[general]\n",
+		"app.conf.d/a.conf": "[general]\n",
+		"app.conf.d/b.conf": "[general]\n",
+	})
+	policy := userConfig.NamingPolicy{GeneralExt: ".cfg"}
+	if _, err := userConfig.MigrateFileNames(dir, "app", policy); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, dir, map[string]bool{
+		"app.cfg":          true,
+		"app.conf":         false,
+		"app.cfg.d/a.cfg":  true,
+		"app.cfg.d/b.cfg":  true,
+		"app.cfg.d/a.conf": false,
+		"app.conf.d":       false,
+	})
+}
+
+func TestMigrateTargetExists(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.conf": "[general]\nold = \"1\"\n",
+		"app.cfg":  "[general]\nnew = \"1\"\n",
+	})
+	renames, err := userConfig.MigrateFileNames(dir, "app", userConfig.NamingPolicy{GeneralExt: ".cfg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renames) != 0 {
+		t.Errorf("renames = %+v, want none", renames)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "app.cfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "new") {
+		t.Errorf("app.cfg was overwritten:\n%s", data)
+	}
+	assertFiles(t, dir, map[string]bool{"app.conf": true})
+}
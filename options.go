@@ -0,0 +1,205 @@
+package userConfig
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Option changes how NewConfig finds and loads a config
+type Option func(*Config)
+
+// WithDirectory uses dir as the config directory instead of the XDG one
+func WithDirectory(dir string) Option {
+	return func(c *Config) {
+		c.dir = dir
+	}
+}
+
+// WithFile uses the .conf file at path as the general config file instead
+// of <name>.conf in the XDG config directory
+func WithFile(path string) Option {
+	return func(c *Config) {
+		c.dir = filepath.Dir(path)
+		c.fileName = filepath.Base(path)
+	}
+}
+
+// WithSearchPaths checks each of dirs in order and uses the first one that
+// has a <name>.conf in it. If none of them do, the XDG directory is used.
+func WithSearchPaths(dirs ...string) Option {
+	return func(c *Config) {
+		c.searchPaths = append(c.searchPaths, dirs...)
+	}
+}
+
+// WithDirMode sets the mode used when creating the config, data and cache
+// directories and any of their missing parents
+func WithDirMode(mode os.FileMode) Option {
+	return func(c *Config) {
+		c.dirMode = mode
+	}
+}
+
+// ReadOnly opens the config without creating any directories or files
+// A missing config returns ErrNotExist, and all setters return ErrReadOnly.
+func ReadOnly(c *Config) {
+	c.readOnly = true
+}
+
+// WithHistoryLimit sets how many changes are kept in the config's journal
+// A negative limit turns the journal off.
+func WithHistoryLimit(n int) Option {
+	return func(c *Config) {
+		c.historyLimit = n
+	}
+}
+
+// WithInterpolation makes Get expand references in values:
+// ${other_key}, ${env:VAR}, ${xdg:config}, ${xdg:data}, ${xdg:cache} and
+// ${addon:name.category.key}
+func WithInterpolation() Option {
+	return func(c *Config) {
+		c.interpolate = true
+	}
+}
+
+// WithSchema declares the type and description of keys for Describe
+func WithSchema(schema map[string]KeySchema) Option {
+	return func(c *Config) {
+		for k, s := range schema {
+			c.Declare(k, s)
+		}
+	}
+}
+
+// WithBytesEncoding sets how SetBytes encodes values, EncodingBase64 by
+// default
+func WithBytesEncoding(enc BytesEncoding) Option {
+	return func(c *Config) {
+		c.bytesEncoding = enc
+	}
+}
+
+// WithBlobLimit sets the size in bytes above which SetBytes writes values to
+// a raw file instead of the config file
+// A negative limit keeps every value in the config file.
+func WithBlobLimit(n int) Option {
+	return func(c *Config) {
+		c.blobLimit = n
+	}
+}
+
+// WithDateTimeLayouts sets the layouts GetDateTime accepts, in the order
+// they're tried, instead of DefaultDateTimeLayouts
+func WithDateTimeLayouts(layouts ...string) Option {
+	return func(c *Config) {
+		c.dateTimeLayouts = layouts
+	}
+}
+
+// WithLocation sets the time zone GetDateTime uses for values that don't
+// have one, time.Local by default
+func WithLocation(loc *time.Location) Option {
+	return func(c *Config) {
+		c.location = loc
+	}
+}
+
+// WithDataDir uses dir as the data directory instead of the XDG one
+func WithDataDir(dir string) Option {
+	return func(c *Config) {
+		c.dataPath = dir
+	}
+}
+
+// WithCacheDir uses dir as the cache directory instead of the XDG one
+func WithCacheDir(dir string) Option {
+	return func(c *Config) {
+		c.cachePath = dir
+	}
+}
+
+// WithFileSystem reads and writes the files in the config directory with fs
+// instead of the disk, see FileSystem
+func WithFileSystem(fs FileSystem) Option {
+	return func(c *Config) {
+		c.fs = fs
+	}
+}
+
+// WithShared imports the keys of the config called name, opened read-only,
+// as "<namespace>.<key>", or just "<key>" if namespace is empty. Only keys
+// are imported if any are given. Configs imported earlier win over later
+// ones, and policy decides what happens when the local config defines the
+// same key. With interpolation on, ${...} references in the shared values
+// are to keys in the shared config.
+func WithShared(name, namespace string, policy ConflictPolicy, keys ...string) Option {
+	return func(c *Config) {
+		c.shared = append(c.shared, sharedImport{name: name, namespace: namespace, policy: policy, keys: keys})
+	}
+}
+
+// WithNaming names the config's files according to p instead of
+// DefaultNaming. Extensions p leaves empty keep their default.
+func WithNaming(p NamingPolicy) Option {
+	return func(c *Config) {
+		c.naming = p
+	}
+}
+
+// WithFileMigration renames files written under older naming conventions,
+// see MigrateFileNames, before loading the config
+func WithFileMigration() Option {
+	return func(c *Config) {
+		c.migrate = true
+	}
+}
+
+// PathFlag is a flag.Value for a --config style flag that can point at
+// either a config directory or a .conf file
+type PathFlag struct {
+	path string
+}
+
+// ConfigFlag registers a "config" flag on fs and returns it
+// Once fs is parsed, pass flag.Option() to NewConfig.
+func ConfigFlag(fs *flag.FlagSet) *PathFlag {
+	p := new(PathFlag)
+	fs.Var(p, "config", "path to a config directory or .conf file")
+	return p
+}
+
+// String returns the path given to the flag
+func (p *PathFlag) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.path
+}
+
+// Set sets the path given to the flag
+func (p *PathFlag) Set(v string) error {
+	p.path = v
+	return nil
+}
+
+// Option returns the Option for the path given to the flag, or an Option
+// that does nothing if the flag wasn't given
+// Pass it after any WithNaming, so it knows the general file's extension.
+func (p *PathFlag) Option() Option {
+	return func(c *Config) {
+		if p == nil || strings.TrimSpace(p.path) == "" {
+			return
+		}
+		if fi, err := os.Stat(p.path); err == nil && fi.IsDir() {
+			WithDirectory(p.path)(c)
+		} else if filepath.Ext(p.path) == c.naming.withDefaults().GeneralExt {
+			WithFile(p.path)(c)
+		} else {
+			WithDirectory(p.path)(c)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build linux
+// +build linux
+
+package userConfig
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkPeer returns an error unless the process on the other end of conn
+// is run by the same user, going by SO_PEERCRED
+func checkPeer(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return err
+	}
+	if credErr != nil {
+		return credErr
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return ErrPeerRejected
+	}
+	return nil
+}
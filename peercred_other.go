@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package userConfig
+
+import (
+	"fmt"
+	"net"
+)
+
+// checkPeer rejects every connection, since there's no way to check who's
+// on the other end of conn here
+func checkPeer(conn *net.UnixConn) error {
+	return fmt.Errorf("can't check peer credentials on this platform: %w", ErrPeerRejected)
+}
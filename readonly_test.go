@@ -0,0 +1,57 @@
+package userConfig_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestReadOnly(t *testing.T) {
+	c := userconfigtest.FromTOML(t, "app", "[general]\nname = \"before\"\n", userConfig.ReadOnly)
+	saved := userconfigtest.SavedContent(t, c)
+
+	for name, err := range map[string]error{
+		"Set":       c.Set("name", "after"),
+		"SetInt":    c.SetInt("count", 1),
+		"SetBytes":  c.SetBytes("blob", []byte("x")),
+		"DeleteKey": c.DeleteKey("name"),
+		"Save":      c.Save(),
+		"Undo":      c.Undo(1),
+		"RestoreAt": c.RestoreAt(time.Now()),
+	} {
+		if !errors.Is(err, userConfig.ErrReadOnly) {
+			t.Errorf("%s on a read-only config returned %v, want ErrReadOnly", name, err)
+		}
+	}
+	if _, err := c.Snapshot(""); !errors.Is(err, userConfig.ErrReadOnly) {
+		t.Errorf("Snapshot on a read-only config returned %v, want ErrReadOnly", err)
+	}
+
+	if v := c.Get("name"); v != "before" {
+		t.Errorf("name = %q, want before", v)
+	}
+	if now := userconfigtest.SavedContent(t, c); now != saved {
+		t.Errorf("read-only config file changed:\n%s", now)
+	}
+	for _, dir := range []string{c.GetDataDir().Path, c.GetCacheDir().Path} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("read-only config created %s", dir)
+		}
+	}
+}
+
+func TestReadOnlyMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "config")
+	_, err := userConfig.NewConfig("app", userConfig.WithDirectory(dir), userConfig.ReadOnly)
+	if !errors.Is(err, userConfig.ErrNotExist) {
+		t.Errorf("opening a missing config read-only returned %v, want ErrNotExist", err)
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("opening a missing config read-only created %s", dir)
+	}
+}
@@ -0,0 +1,111 @@
+package userConfig
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrSharedConflict is returned when a shared config and the local one both
+// define a key, and the import was made with ConflictError
+var ErrSharedConflict = errors.New("key defined in both shared and local config")
+
+// ConflictPolicy decides which value is used when a shared config and the
+// local one both define a key
+type ConflictPolicy int
+
+const (
+	// LocalWins uses the local value, the shared one is only a default
+	LocalWins ConflictPolicy = iota
+	// SharedWins uses the shared value, and setting the key locally returns
+	// ErrReadOnly
+	SharedWins
+	// ConflictError fails to load the config with ErrSharedConflict
+	ConflictError
+)
+
+// sharedImport is a config imported with WithShared
+type sharedImport struct {
+	name      string
+	namespace string
+	policy    ConflictPolicy
+	keys      []string
+}
+
+// Open opens the config of another app called name, with the same file
+// system, directory mode, naming and interpolation as c, then opts
+// Use Open("otherapp", ReadOnly) to just look at it.
+func (c *Config) Open(name string, opts ...Option) (*Config, error) {
+	inherit := []Option{WithDirMode(c.dirMode), WithFileSystem(c.fs), WithNaming(c.naming)}
+	if c.interpolate {
+		inherit = append(inherit, WithInterpolation())
+	}
+	return NewConfig(name, append(inherit, opts...)...)
+}
+
+// importShared opens every shared config, read-only, and adds its keys to
+// gf according to the import's ConflictPolicy
+// A shared config that doesn't exist is skipped. With interpolation on,
+// values are imported as the shared config's Get returns them.
+func (c *Config) importShared(gf *GeneralConfig) error {
+	var overlays, layers []configLayer
+	gf.mu.Lock()
+	gf.overlays = nil
+	gf.mu.Unlock()
+	local := gf.effectiveValues()
+	for _, imp := range c.shared {
+		shared, err := c.Open(imp.name, ReadOnly)
+		if errors.Is(err, ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		sharedGf := shared.general()
+		keys := imp.keys
+		if len(keys) == 0 {
+			keys = sharedGf.GetKeyList()
+		}
+
+		layer := configLayer{path: sharedGf.GetFullPath(), values: make(map[string]string)}
+		for _, k := range keys {
+			v, ok := sharedGf.lookup(k)
+			if !ok {
+				continue
+			}
+			if c.interpolate {
+				// References are to keys in the shared config, so they're
+				// expanded there and what's left is kept from being
+				// expanded again in ours
+				v = strings.ReplaceAll(sharedGf.Get(k), "${", "$${")
+			}
+			if imp.namespace != "" {
+				k = imp.namespace + "." + k
+			}
+			if _, ok := local[k]; ok && imp.policy == ConflictError {
+				return &ConfigError{Op: "import", Path: layer.path, Key: k, Err: ErrSharedConflict}
+			}
+			layer.values[k] = v
+		}
+		if imp.policy == SharedWins {
+			overlays = append(overlays, layer)
+		} else {
+			layers = append(layers, layer)
+		}
+	}
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	gf.overlays = overlays
+	gf.layers = append(gf.layers, layers...)
+	return nil
+}
+
+// shadowed returns whether k's value comes from a shared config that wins
+// over the main file, so setting it there would do nothing
+// gf.mu has to be held.
+func (gf *GeneralConfig) shadowed(k string) bool {
+	for _, l := range gf.overlays {
+		if _, ok := l.values[k]; ok {
+			return true
+		}
+	}
+	return false
+}
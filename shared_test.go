@@ -0,0 +1,44 @@
+package userConfig_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+func TestSharedReferences(t *testing.T) {
+	home := t.TempDir()
+	for _, v := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME"} {
+		t.Setenv(v, filepath.Join(home, v))
+	}
+	teamDir := filepath.Join(home, "XDG_CONFIG_HOME", "team")
+	if err := os.MkdirAll(teamDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	shared := "[general]\nhost = \"team.example.com\"\napi = \"https://${host}/api\"\nliteral = \"$${host}\"\n"
+	if err := ioutil.WriteFile(filepath.Join(teamDir, "team.conf"), []byte(shared), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := userConfig.NewConfig("app", userConfig.WithInterpolation(),
+		userConfig.WithShared("team", "team", userConfig.LocalWins))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A local key with the same name as the one the shared value refers to
+	if err = c.Set("host", "local.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]string{
+		"team.api":     "https://team.example.com/api",
+		"team.literal": "${host}",
+		"host":         "local.example.com",
+	} {
+		if v := c.Get(k); v != want {
+			t.Errorf("%s = %q, want %q", k, v, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package userConfig
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotDir is where snapshots are kept in the XDG data directory
+const snapshotDir = "snapshots"
+
+// snapshotTimeLayout is the time at the start of every snapshot ID
+const snapshotTimeLayout = "20060102T150405.000Z"
+
+// Anything else in a label is replaced so it's safe in a file name
+var snapshotLabelRegexp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// SnapshotInfo describes a snapshot of the config directory
+type SnapshotInfo struct {
+	ID    string
+	Label string
+	Time  time.Time
+	Path  string
+	Size  int64
+}
+
+// Snapshot archives the .conf file, every addon listed in additional_config
+// and every raw file into the XDG data directory
+func (c *Config) Snapshot(label string) (*SnapshotInfo, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.snapshot(label)
+}
+
+// snapshot is Snapshot for callers that hold c.writeMu
+func (c *Config) snapshot(label string) (*SnapshotInfo, error) {
+	if c.general() == nil {
+		return nil, &ConfigError{Op: "snapshot", Path: c.name, Err: ErrNotLoaded}
+	}
+	if c.readOnly {
+		return nil, &ConfigError{Op: "snapshot", Path: c.name, Err: ErrReadOnly}
+	}
+	snaps := NewAppDir(c.dataDir.GetFullPath(snapshotDir))
+	snaps.Mode = c.dataDir.Mode
+	if err := snaps.Verify(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	id := now.Format(snapshotTimeLayout)
+	label = strings.Trim(snapshotLabelRegexp.ReplaceAllString(label, "-"), "-")
+	if label != "" {
+		id += "-" + label
+	}
+	snapPath := snaps.GetFullPath(id + ".tar.gz")
+	if err := c.writeSnapshot(snapPath); err != nil {
+		os.Remove(snapPath)
+		return nil, &ConfigError{Op: "snapshot", Path: snapPath, Err: err}
+	}
+	return readSnapshotInfo(snapPath)
+}
+
+// ListSnapshots returns every snapshot of the config, oldest first
+func (c *Config) ListSnapshots() ([]SnapshotInfo, error) {
+	var ret []SnapshotInfo
+	if c.dataDir == nil {
+		return ret, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(c.dataDir.GetFullPath(snapshotDir), "*.tar.gz"))
+	if err != nil {
+		return ret, err
+	}
+	for _, p := range paths {
+		if info, err := readSnapshotInfo(p); err == nil {
+			ret = append(ret, *info)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Time.Before(ret[j].Time)
+	})
+	return ret, nil
+}
+
+// RestoreSnapshot puts the files from the snapshot id back into the config
+// directory and reloads the config, without asking any OnValidate callbacks
+// The current files are snapshotted first so the restore can be undone.
+func (c *Config) RestoreSnapshot(id string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.general() == nil {
+		return &ConfigError{Op: "restore", Path: c.name, Err: ErrNotLoaded}
+	}
+	if c.readOnly {
+		return &ConfigError{Op: "restore", Path: c.name, Err: ErrReadOnly}
+	}
+	snapPath := filepath.Join(c.dataDir.GetFullPath(snapshotDir), filepath.Base(id)+".tar.gz")
+	if _, err := os.Stat(snapPath); err != nil {
+		return &ConfigError{Op: "restore", Path: snapPath, Err: err}
+	}
+	if _, err := c.snapshot("pre-restore"); err != nil {
+		return err
+	}
+	if err := c.extractSnapshot(snapPath); err != nil {
+		return &ConfigError{Op: "restore", Path: snapPath, Err: err}
+	}
+	return c.reloadLocked(false)
+}
+
+// snapshotFiles returns the paths, relative to the config directory, of
+// every file that goes into a snapshot
+func (c *Config) snapshotFiles() []string {
+	gf := c.general()
+	files := []string{filepath.Base(gf.GetFullPath())}
+	gf.mu.RLock()
+	defer gf.mu.RUnlock()
+	for _, name := range gf.ConfigFiles {
+		files = append(files, name+c.naming.AddonExt)
+	}
+	return append(files, gf.RawFiles...)
+}
+
+// writeSnapshot writes a gzipped tar of the snapshot files to snapPath
+func (c *Config) writeSnapshot(snapPath string) error {
+	f, err := os.Create(snapPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for _, name := range c.snapshotFiles() {
+		data, err := c.fs.ReadFile(filepath.Join(c.GetConfigPath(), name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    filepath.ToSlash(name),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err = tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// extractSnapshot writes the files in the snapshot at snapPath into the
+// config directory
+func (c *Config) extractSnapshot(snapPath string) error {
+	f, err := os.Open(snapPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(os.PathSeparator)) {
+			// Never write outside of the config directory
+			continue
+		}
+		dest := filepath.Join(c.GetConfigPath(), name)
+		if err = c.fs.MkdirAll(filepath.Dir(dest), c.dirMode); err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err = c.fs.WriteFile(dest, data, 0644); err != nil {
+			return err
+		}
+	}
+}
+
+// readSnapshotInfo builds the SnapshotInfo for the snapshot at snapPath
+func readSnapshotInfo(snapPath string) (*SnapshotInfo, error) {
+	fi, err := os.Stat(snapPath)
+	if err != nil {
+		return nil, err
+	}
+	info := &SnapshotInfo{
+		ID:   strings.TrimSuffix(filepath.Base(snapPath), ".tar.gz"),
+		Path: snapPath,
+		Size: fi.Size(),
+	}
+	stamp := info.ID
+	if i := strings.Index(info.ID, "-"); i >= 0 {
+		stamp, info.Label = info.ID[:i], info.ID[i+1:]
+	}
+	if info.Time, err = time.Parse(snapshotTimeLayout, stamp); err != nil {
+		info.Time = fi.ModTime()
+	}
+	return info, nil
+}
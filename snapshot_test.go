@@ -0,0 +1,65 @@
+package userConfig_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+// writeArchive writes a snapshot archive with files in it to path
+func writeArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for name, data := range files {
+		if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreSnapshotStaysInConfigDir(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	if _, err := c.Snapshot(""); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(filepath.Dir(c.GetConfigPath()), "escaped")
+	abs := filepath.Join(t.TempDir(), "absolute")
+	snapDir := c.GetDataDir().GetFullPath("snapshots")
+	writeArchive(t, filepath.Join(snapDir, "20200101T000000.000Z-evil.tar.gz"), map[string]string{
+		"app.conf":            "[general]\nrestored = \"yes\"\n",
+		"../escaped":          "outside",
+		"sub/../../escaped":   "outside",
+		filepath.ToSlash(abs): "outside",
+	})
+
+	if err := c.RestoreSnapshot("20200101T000000.000Z-evil"); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Get("restored"); v != "yes" {
+		t.Errorf("restored = %q, want yes", v)
+	}
+	for _, p := range []string{outside, abs} {
+		if _, err := os.Stat(p); err == nil {
+			t.Errorf("restoring wrote %s, outside the config directory", p)
+		}
+	}
+}
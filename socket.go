@@ -0,0 +1,425 @@
+package userConfig
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPeerRejected is returned when a process that isn't run by the same
+	// user connects to a config's control socket
+	ErrPeerRejected = errors.New("peer is not the same user")
+	// ErrSocketInUse is returned when another process is already serving
+	// the config's control socket
+	ErrSocketInUse = errors.New("control socket in use")
+)
+
+// socketFile is the name of the control socket in the runtime directory
+const socketFile = "config.sock"
+
+// socketWriteTimeout is how long a client that isn't reading gets before
+// it's dropped, so it can't hold up the others
+const socketWriteTimeout = 5 * time.Second
+
+// SocketRequest is one line sent to a control socket
+// Op is one of "get", "set", "delete" or "subscribe".
+type SocketRequest struct {
+	ID      int    `json:"id"`
+	Op      string `json:"op"`
+	Key     string `json:"key,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// SocketResponse is one line sent back by a control socket, either the
+// answer to the request with the same ID or, with an ID of 0, a change to a
+// subscribed key
+type SocketResponse struct {
+	ID     int      `json:"id"`
+	OK     bool     `json:"ok"`
+	Value  string   `json:"value,omitempty"`
+	Error  string   `json:"error,omitempty"`
+	Change *KeyDiff `json:"change,omitempty"`
+}
+
+// SocketPath returns where the control socket of the config called name
+// lives, $XDG_RUNTIME_DIR/<name>/config.sock
+// name isn't checked, see ValidateAppName.
+func SocketPath(name string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "user-config-"+strconv.Itoa(os.Getuid()))
+	}
+	return filepath.Join(dir, name, socketFile)
+}
+
+// SocketPath returns where the config's control socket lives
+func (c *Config) SocketPath() string {
+	return SocketPath(c.name)
+}
+
+// SocketServer lets other processes run by the same user get, set, delete
+// and subscribe to keys in a running Config over a Unix socket
+type SocketServer struct {
+	c       *Config
+	ln      *net.UnixListener
+	path    string
+	watcher *keyWatcher
+	mu      sync.Mutex
+	conns   map[*socketConn]bool
+	done    bool
+}
+
+// socketConn is a client connected to a SocketServer
+type socketConn struct {
+	conn     *net.UnixConn
+	mu       sync.Mutex
+	enc      *json.Encoder
+	patterns []string
+}
+
+// ListenSocket starts serving the config on its control socket
+// Changes are sent to subscribers through OnChange, so they include
+// Reloads as well as changes made through the socket.
+func (c *Config) ListenSocket() (*SocketServer, error) {
+	if c.general() == nil {
+		return nil, &ConfigError{Op: "listen", Path: c.name, Err: ErrNotLoaded}
+	}
+	sockPath := c.SocketPath()
+	if err := verifyOrCreateDirectory(filepath.Dir(sockPath), 0700); err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(sockPath); err == nil {
+		if conn, err := net.Dial("unix", sockPath); err == nil {
+			conn.Close()
+			return nil, &ConfigError{Op: "listen", Path: sockPath, Err: ErrSocketInUse}
+		}
+		// Left behind by a process that's gone
+		os.Remove(sockPath)
+	}
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: sockPath, Net: "unix"})
+	if err != nil {
+		return nil, &ConfigError{Op: "listen", Path: sockPath, Err: err}
+	}
+	if err = os.Chmod(sockPath, 0600); err != nil {
+		ln.Close()
+		return nil, &ConfigError{Op: "listen", Path: sockPath, Err: err}
+	}
+	s := &SocketServer{c: c, ln: ln, path: sockPath, conns: make(map[*socketConn]bool)}
+	s.watcher = c.onKeyChange("*", s.broadcast)
+	go s.serve()
+	return s, nil
+}
+
+// Close stops accepting connections, disconnects every client and removes
+// the socket
+func (s *SocketServer) Close() error {
+	s.c.removeWatcher(s.watcher)
+	s.mu.Lock()
+	s.done = true
+	for sc := range s.conns {
+		sc.conn.Close()
+	}
+	s.mu.Unlock()
+	return s.ln.Close()
+}
+
+// serve accepts connections until the server is closed
+func (s *SocketServer) serve() {
+	for {
+		conn, err := s.ln.AcceptUnix()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers the requests from one client
+func (s *SocketServer) handle(conn *net.UnixConn) {
+	sc := &socketConn{conn: conn, enc: json.NewEncoder(conn)}
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		sc.send(SocketResponse{Error: err.Error()})
+		return
+	}
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.conns[sc] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, sc)
+		s.mu.Unlock()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, maxValueSize)
+	for scanner.Scan() {
+		var req SocketRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			sc.send(SocketResponse{Error: err.Error()})
+			continue
+		}
+		resp := s.do(sc, req)
+		resp.ID = req.ID
+		if err := sc.send(resp); err != nil {
+			return
+		}
+	}
+}
+
+// do carries out a single request
+func (s *SocketServer) do(sc *socketConn, req SocketRequest) SocketResponse {
+	var err error
+	switch req.Op {
+	case "get":
+		gf := s.c.general()
+		if _, ok := gf.lookup(req.Key); !ok {
+			err = gf.keyError("get", req.Key, ErrNoKey)
+			break
+		}
+		return SocketResponse{OK: true, Value: gf.Get(req.Key)}
+	case "set":
+		// Config's setters are safe to call from every connection at once
+		err = s.c.Set(req.Key, req.Value)
+	case "delete":
+		err = s.c.DeleteKey(req.Key)
+	case "subscribe":
+		pattern := req.Pattern
+		if pattern == "" {
+			pattern = "*"
+		}
+		sc.mu.Lock()
+		sc.patterns = append(sc.patterns, pattern)
+		sc.mu.Unlock()
+	default:
+		err = errors.New("unknown op " + strconv.Quote(req.Op))
+	}
+	if err != nil {
+		// The client adds its own op and key
+		var ce *ConfigError
+		if errors.As(err, &ce) {
+			err = ce.Err
+		}
+		return SocketResponse{Error: err.Error()}
+	}
+	return SocketResponse{OK: true}
+}
+
+// socketErrors are the errors a client turns back into the sentinel, so
+// they can still be checked with errors.Is
+var socketErrors = []error{ErrNoKey, ErrReadOnly, ErrNotLoaded, ErrPermission, ErrNotExist, ErrPeerRejected}
+
+// socketError returns the error a SocketResponse carries
+func socketError(msg string) error {
+	for _, e := range socketErrors {
+		if msg == e.Error() {
+			return e
+		}
+	}
+	return errors.New(msg)
+}
+
+// broadcast sends a change to every client subscribed to its key
+// A client that can't be sent to is disconnected, so it doesn't miss
+// changes without knowing.
+func (s *SocketServer) broadcast(k, old, new string) {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	var subs []*socketConn
+	for sc := range s.conns {
+		if sc.subscribed(k) {
+			subs = append(subs, sc)
+		}
+	}
+	s.mu.Unlock()
+
+	// Sending can take up to socketWriteTimeout, which mustn't hold up
+	// clients connecting or leaving
+	for _, sc := range subs {
+		if err := sc.send(SocketResponse{OK: true, Change: &KeyDiff{Key: k, Old: old, New: new}}); err != nil {
+			sc.conn.Close()
+			s.mu.Lock()
+			delete(s.conns, sc)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// subscribed reports whether the client wants changes to k
+func (sc *socketConn) subscribed(k string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, p := range sc.patterns {
+		if keyMatches(p, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// send writes one response line to the client
+func (sc *socketConn) send(resp SocketResponse) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	return sc.enc.Encode(resp)
+}
+
+// SocketClient talks to the control socket of a running Config
+type SocketClient struct {
+	path    string
+	conn    net.Conn
+	mu      sync.Mutex
+	enc     *json.Encoder
+	nextID  int
+	pending map[int]chan SocketResponse
+	changes chan KeyDiff
+	err     error
+}
+
+// DialSocket connects to the control socket of the config called name
+func DialSocket(name string) (*SocketClient, error) {
+	// Keep the name from pointing outside the runtime directory
+	if err := ValidateAppName(name); err != nil {
+		return nil, &ConfigError{Op: "dial", Path: name, Err: err}
+	}
+	sockPath := SocketPath(name)
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		return nil, &ConfigError{Op: "dial", Path: sockPath, Err: err}
+	}
+	cl := &SocketClient{
+		path:    sockPath,
+		conn:    conn,
+		enc:     json.NewEncoder(conn),
+		pending: make(map[int]chan SocketResponse),
+		changes: make(chan KeyDiff, 64),
+	}
+	go cl.read()
+	return cl, nil
+}
+
+// Get returns the value of k in the running config
+func (cl *SocketClient) Get(k string) (string, error) {
+	resp, err := cl.call(SocketRequest{Op: "get", Key: k})
+	return resp.Value, err
+}
+
+// Set sets k to v in the running config, which saves it
+func (cl *SocketClient) Set(k, v string) error {
+	_, err := cl.call(SocketRequest{Op: "set", Key: k, Value: v})
+	return err
+}
+
+// DeleteKey removes k from the running config, which saves it
+func (cl *SocketClient) DeleteKey(k string) error {
+	_, err := cl.call(SocketRequest{Op: "delete", Key: k})
+	return err
+}
+
+// Subscribe asks for changes to the keys matching pattern to be sent to the
+// channel returned by Changes
+func (cl *SocketClient) Subscribe(pattern string) error {
+	_, err := cl.call(SocketRequest{Op: "subscribe", Pattern: pattern})
+	return err
+}
+
+// Changes returns the channel subscribed changes are sent to, it's closed
+// when the connection is
+// Changes that arrive while the channel's buffer is full are dropped, so a
+// client that doesn't read them can still make calls.
+func (cl *SocketClient) Changes() <-chan KeyDiff {
+	return cl.changes
+}
+
+// Close disconnects from the control socket
+func (cl *SocketClient) Close() error {
+	return cl.conn.Close()
+}
+
+// call sends req and waits for the response to it
+func (cl *SocketClient) call(req SocketRequest) (SocketResponse, error) {
+	cl.mu.Lock()
+	if cl.err != nil {
+		cl.mu.Unlock()
+		return SocketResponse{}, &ConfigError{Op: req.Op, Path: cl.path, Key: req.Key, Err: cl.err}
+	}
+	cl.nextID++
+	req.ID = cl.nextID
+	ch := make(chan SocketResponse, 1)
+	cl.pending[req.ID] = ch
+	err := cl.enc.Encode(req)
+	cl.mu.Unlock()
+	if err != nil {
+		return SocketResponse{}, &ConfigError{Op: req.Op, Path: cl.path, Key: req.Key, Err: err}
+	}
+	resp, ok := <-ch
+	if !ok {
+		return resp, &ConfigError{Op: req.Op, Path: cl.path, Key: req.Key, Err: cl.err}
+	}
+	if !resp.OK {
+		return resp, &ConfigError{Op: req.Op, Path: cl.path, Key: req.Key, Err: socketError(resp.Error)}
+	}
+	return resp, nil
+}
+
+// read hands each response to the call waiting for it, and each change to
+// the changes channel, until the connection is closed
+func (cl *SocketClient) read() {
+	scanner := bufio.NewScanner(cl.conn)
+	scanner.Buffer(nil, maxValueSize)
+	for scanner.Scan() {
+		var resp SocketResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			continue
+		}
+		if resp.Change != nil {
+			select {
+			case cl.changes <- *resp.Change:
+			default:
+				// Nobody's reading, don't hold up the responses behind it
+			}
+			continue
+		}
+		cl.mu.Lock()
+		ch, ok := cl.pending[resp.ID]
+		delete(cl.pending, resp.ID)
+		if !ok && resp.ID == 0 && resp.Error != "" {
+			// Rejected before any request was answered
+			cl.err = socketError(resp.Error)
+		}
+		cl.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+
+	cl.mu.Lock()
+	if cl.err == nil {
+		cl.err = scanner.Err()
+		if cl.err == nil {
+			cl.err = net.ErrClosed
+		}
+	}
+	for id, ch := range cl.pending {
+		close(ch)
+		delete(cl.pending, id)
+	}
+	cl.mu.Unlock()
+	close(cl.changes)
+}
@@ -0,0 +1,96 @@
+package userConfig_test
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestSocketConcurrentSets(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	c := userconfigtest.New(t, "app")
+	s, err := c.ListenSocket()
+	if err != nil {
+		t.Skip("can't listen on a control socket here:", err)
+	}
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			cl, err := userConfig.DialSocket("app")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer cl.Close()
+			for j := 0; j < 10; j++ {
+				if err := cl.Set(k, strconv.Itoa(j)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			if err := cl.DeleteKey(k + "_gone"); err != nil {
+				t.Error(err)
+			}
+		}("k" + strconv.Itoa(i))
+	}
+	wg.Wait()
+	for i := 0; i < 4; i++ {
+		userconfigtest.AssertSaved(t, c, "k"+strconv.Itoa(i), "9")
+	}
+}
+
+func TestDialSocketInvalidName(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	for _, name := range []string{"../../x", "/abs", ""} {
+		if _, err := userConfig.DialSocket(name); !errors.Is(err, userConfig.ErrInvalidName) {
+			t.Errorf("DialSocket(%q) returned %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestSocketUnreadChanges(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	c := userconfigtest.New(t, "app")
+	s, err := c.ListenSocket()
+	if err != nil {
+		t.Skip("can't listen on a control socket here:", err)
+	}
+	defer s.Close()
+
+	cl, err := userConfig.DialSocket("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	if err = cl.Subscribe("*"); err != nil {
+		t.Fatal(err)
+	}
+	// Far more changes than Changes buffers, none of them read
+	for i := 0; i < 200; i++ {
+		if err = cl.Set("k", strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := cl.Get("k")
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get hung behind unread changes")
+	}
+}
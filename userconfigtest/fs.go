@@ -0,0 +1,202 @@
+package userconfigtest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+)
+
+// ErrInjected is the error FaultyFS fails with when no other error was set
+var ErrInjected = errors.New("userconfigtest: injected failure")
+
+// MemFS is a userConfig.FileSystem that only keeps files in memory
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+// NewMemFS returns an empty MemFS
+func NewMemFS() *MemFS {
+	return &MemFS{files: make(map[string][]byte), dirs: make(map[string]bool)}
+}
+
+// ReadFile returns the contents of the file name
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// WriteFile replaces the contents of the file name with data
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if dir := filepath.Dir(name); !m.dirs[dir] {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	m.files[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// MkdirAll creates the directory path and all of its parents
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := filepath.Clean(path); !m.dirs[dir]; dir = filepath.Dir(dir) {
+		if _, isFile := m.files[dir]; isFile {
+			return &os.PathError{Op: "mkdir", Path: dir, Err: userConfig.ErrNotDirectory}
+		}
+		m.dirs[dir] = true
+	}
+	return nil
+}
+
+// Remove removes the file name
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// Glob returns the files matching pattern, sorted
+func (m *MemFS) Glob(pattern string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ret []string
+	for name := range m.files {
+		ok, err := filepath.Match(pattern, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// Content returns the contents of the file name, or an empty string if
+// it doesn't exist
+func (m *MemFS) Content(name string) string {
+	data, _ := m.ReadFile(name)
+	return string(data)
+}
+
+// FaultyFS wraps a userConfig.FileSystem and fails reads or writes on
+// demand, to exercise the error paths of code using a Config
+type FaultyFS struct {
+	userConfig.FileSystem
+
+	mu       sync.Mutex
+	writeErr error
+	readErr  error
+	corrupt  bool
+}
+
+// FailWrites makes every write fail with err (ErrInjected if err is nil)
+// until Reset is called
+func (f *FaultyFS) FailWrites(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		err = ErrInjected
+	}
+	f.writeErr = err
+}
+
+// FailReads makes every read fail with err (ErrInjected if err is nil)
+// until Reset is called
+func (f *FaultyFS) FailReads(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		err = ErrInjected
+	}
+	f.readErr = err
+}
+
+// CorruptReads makes every read return data that isn't valid TOML until
+// Reset is called
+func (f *FaultyFS) CorruptReads() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.corrupt = true
+}
+
+// Reset stops injecting failures
+func (f *FaultyFS) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writeErr, f.readErr, f.corrupt = nil, nil, false
+}
+
+// ReadFile reads the file name, unless reads are failing or corrupt
+func (f *FaultyFS) ReadFile(name string) ([]byte, error) {
+	f.mu.Lock()
+	readErr, corrupt := f.readErr, f.corrupt
+	f.mu.Unlock()
+	if readErr != nil {
+		return nil, &os.PathError{Op: "read", Path: name, Err: readErr}
+	}
+	if corrupt {
+		return []byte("[general\nthis is = = not toml\n"), nil
+	}
+	return f.FileSystem.ReadFile(name)
+}
+
+// WriteFile writes data to the file name, unless writes are failing
+func (f *FaultyFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f.mu.Lock()
+	writeErr := f.writeErr
+	f.mu.Unlock()
+	if writeErr != nil {
+		return &os.PathError{Op: "write", Path: name, Err: writeErr}
+	}
+	return f.FileSystem.WriteFile(name, data, perm)
+}
+
+// Remove removes the file name, unless writes are failing
+func (f *FaultyFS) Remove(name string) error {
+	f.mu.Lock()
+	writeErr := f.writeErr
+	f.mu.Unlock()
+	if writeErr != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: writeErr}
+	}
+	return f.FileSystem.Remove(name)
+}
+
+// Glob returns the files matching pattern, unless reads are failing
+func (f *FaultyFS) Glob(pattern string) ([]string, error) {
+	f.mu.Lock()
+	readErr := f.readErr
+	f.mu.Unlock()
+	if readErr != nil {
+		return nil, &os.PathError{Op: "glob", Path: pattern, Err: readErr}
+	}
+	return f.FileSystem.Glob(pattern)
+}
+
+// AssertSaved fails the test unless the general config file of c, which
+// has to come from NewMemory, has k saved with the value want
+func (m *MemFS) AssertSaved(tb testing.TB, c *userConfig.Config, k, want string) {
+	tb.Helper()
+	assertValue(tb, c.GetFullPath(), m.Content(c.GetFullPath()), k, &want)
+}
@@ -0,0 +1,117 @@
+// Package userconfigtest provides hermetic Configs for testing code that
+// uses package userConfig, without touching the real $HOME
+package userconfigtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	userConfig "github.com/br0xen/user-config"
+)
+
+// New returns a Config for name with its config, data and cache
+// directories in a temporary directory that's removed when the test ends
+func New(tb testing.TB, name string, opts ...userConfig.Option) *userConfig.Config {
+	tb.Helper()
+	dir := tb.TempDir()
+	c, err := userConfig.NewConfig(name, append(tempDirOptions(dir), opts...)...)
+	if err != nil {
+		tb.Fatalf("userconfigtest: couldn't create config %s: %v", name, err)
+	}
+	return c
+}
+
+// NewMemory returns a Config for name whose general config file only
+// exists in the returned MemFS
+func NewMemory(tb testing.TB, name string, opts ...userConfig.Option) (*userConfig.Config, *MemFS) {
+	tb.Helper()
+	fs := NewMemFS()
+	c := New(tb, name, append([]userConfig.Option{userConfig.WithFileSystem(fs)}, opts...)...)
+	return c, fs
+}
+
+// NewFaulty returns a Config for name in a temporary directory, along with
+// the FaultyFS its general config file goes through
+func NewFaulty(tb testing.TB, name string, opts ...userConfig.Option) (*userConfig.Config, *FaultyFS) {
+	tb.Helper()
+	fs := &FaultyFS{FileSystem: userConfig.OSFileSystem{}}
+	c := New(tb, name, append([]userConfig.Option{userConfig.WithFileSystem(fs)}, opts...)...)
+	return c, fs
+}
+
+// FromTOML returns a Config for name in a temporary directory, with its
+// general config file starting out as data
+func FromTOML(tb testing.TB, name, data string, opts ...userConfig.Option) *userConfig.Config {
+	tb.Helper()
+	dir := tb.TempDir()
+	cfgDir := filepath.Join(dir, "config")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		tb.Fatalf("userconfigtest: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(cfgDir, name+userConfig.DefaultNaming.GeneralExt), []byte(data), 0644); err != nil {
+		tb.Fatalf("userconfigtest: %v", err)
+	}
+	c, err := userConfig.NewConfig(name, append(tempDirOptions(dir), opts...)...)
+	if err != nil {
+		tb.Fatalf("userconfigtest: couldn't load config %s: %v", name, err)
+	}
+	return c
+}
+
+// SavedContent returns what's currently saved in c's general config file
+// Configs from NewMemory should use MemFS.Content or MemFS.AssertSaved
+// instead.
+func SavedContent(tb testing.TB, c *userConfig.Config) string {
+	tb.Helper()
+	data, err := ioutil.ReadFile(c.GetFullPath())
+	if err != nil {
+		tb.Fatalf("userconfigtest: couldn't read %s: %v", c.GetFullPath(), err)
+	}
+	return string(data)
+}
+
+// AssertSaved fails the test unless c's general config file has k saved
+// with the value want
+func AssertSaved(tb testing.TB, c *userConfig.Config, k, want string) {
+	tb.Helper()
+	assertValue(tb, c.GetFullPath(), SavedContent(tb, c), k, &want)
+}
+
+// AssertNotSaved fails the test if c's general config file has k saved
+func AssertNotSaved(tb testing.TB, c *userConfig.Config, k string) {
+	tb.Helper()
+	assertValue(tb, c.GetFullPath(), SavedContent(tb, c), k, nil)
+}
+
+// assertValue checks the value of k in the saved content of a general
+// config file, nil want means k shouldn't be there
+func assertValue(tb testing.TB, path, content, k string, want *string) {
+	tb.Helper()
+	var saved struct {
+		Values map[string]string `toml:"general"`
+	}
+	if _, err := toml.Decode(content, &saved); err != nil {
+		tb.Fatalf("userconfigtest: %s isn't valid: %v", path, err)
+	}
+	got, ok := saved.Values[k]
+	switch {
+	case want == nil && ok:
+		tb.Errorf("userconfigtest: %s has %s = %q saved, want it unset", path, k, got)
+	case want != nil && !ok:
+		tb.Errorf("userconfigtest: %s doesn't have %s saved, want %q", path, k, *want)
+	case want != nil && got != *want:
+		tb.Errorf("userconfigtest: %s has %s = %q saved, want %q", path, k, got, *want)
+	}
+}
+
+// tempDirOptions keeps every directory a Config uses inside dir
+func tempDirOptions(dir string) []userConfig.Option {
+	return []userConfig.Option{
+		userConfig.WithDirectory(filepath.Join(dir, "config")),
+		userConfig.WithDataDir(filepath.Join(dir, "data")),
+		userConfig.WithCacheDir(filepath.Join(dir, "cache")),
+	}
+}
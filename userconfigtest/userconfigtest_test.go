@@ -0,0 +1,73 @@
+package userconfigtest_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	userConfig "github.com/br0xen/user-config"
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestSetRevertsOnWriteError(t *testing.T) {
+	c, fs := userconfigtest.NewFaulty(t, "app")
+	if err := c.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.FailWrites(nil)
+	if err := c.Set("a", "2"); !errors.Is(err, userconfigtest.ErrInjected) {
+		t.Fatalf("Set with failing writes returned %v, want ErrInjected", err)
+	}
+	if err := c.Set("b", "new"); !errors.Is(err, userconfigtest.ErrInjected) {
+		t.Fatalf("Set with failing writes returned %v, want ErrInjected", err)
+	}
+	if v := c.Get("a"); v != "1" {
+		t.Errorf("a = %q after a failed Set, want 1", v)
+	}
+	if keys := c.GetKeyList(); len(keys) != 1 || keys[0] != "a" {
+		t.Errorf("keys after a failed Set of a new key = %v, want [a]", keys)
+	}
+
+	fs.Reset()
+	userconfigtest.AssertSaved(t, c, "a", "1")
+	userconfigtest.AssertNotSaved(t, c, "b")
+}
+
+func TestMemoryBlobs(t *testing.T) {
+	c, fs := userconfigtest.NewMemory(t, "app", userConfig.WithBlobLimit(4))
+	want := []byte("more than four bytes")
+	if err := c.SetBytes("big", want); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetBytes("big"); !bytes.Equal(got, want) {
+		t.Errorf("big = %q, want %q", got, want)
+	}
+	inMemory, _ := fs.Glob(filepath.Join(c.GetConfigPath(), "*.bin"))
+	onDisk, _ := filepath.Glob(filepath.Join(c.GetConfigPath(), "*.bin"))
+	if len(inMemory) != 1 || len(onDisk) != 0 {
+		t.Errorf("raw files in memory %v and on disk %v, want one in memory", inMemory, onDisk)
+	}
+}
+
+func TestMemorySnapshot(t *testing.T) {
+	c, fs := userconfigtest.NewMemory(t, "app")
+	if err := c.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.Snapshot("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Set("a", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.RestoreSnapshot(info.ID); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Get("a"); v != "1" {
+		t.Errorf("a = %q after restoring, want 1", v)
+	}
+	fs.AssertSaved(t, c, "a", "1")
+}
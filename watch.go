@@ -0,0 +1,272 @@
+package userConfig
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// keyWatcher is a callback registered with OnChange
+type keyWatcher struct {
+	pattern string
+	fn      func(k, old, new string)
+}
+
+// keyValidator is a callback registered with OnValidate
+type keyValidator struct {
+	pattern string
+	fn      func(old, new string) error
+}
+
+// OnChange calls fn with the old and new value whenever a key matching
+// pattern (as in path.Match, like "log_*") changes, either through a setter
+// or a Reload. Callbacks run one at a time, in the order the changes
+// happened, on a goroutine of their own.
+func (c *Config) OnChange(pattern string, fn func(old, new string)) {
+	c.onKeyChange(pattern, func(k, old, new string) { fn(old, new) })
+}
+
+// onKeyChange is OnChange for callbacks that need to know which key
+// changed, it returns the watcher for removeWatcher
+func (c *Config) onKeyChange(pattern string, fn func(k, old, new string)) *keyWatcher {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &keyWatcher{pattern: pattern, fn: fn}
+	c.watchers = append(c.watchers, w)
+	if c.events == nil {
+		c.events = newDispatcher()
+	}
+	return w
+}
+
+// removeWatcher stops calling a watcher added by onKeyChange
+func (c *Config) removeWatcher(w *keyWatcher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.watchers {
+		if c.watchers[i] == w {
+			c.watchers = append(c.watchers[:i:i], c.watchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// OnValidate calls fn before a Reload changes a key matching pattern
+// If fn returns an error, the reload is rejected and the old values are
+// kept. fn is called while the config is locked for the reload, so it
+// mustn't change the config.
+func (c *Config) OnValidate(pattern string, fn func(old, new string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validators = append(c.validators, keyValidator{pattern: pattern, fn: fn})
+}
+
+// Reload reads the config files again and passes any changed values to the
+// OnChange callbacks, unless an OnValidate callback rejects them
+func (c *Config) Reload() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.reloadLocked(true)
+}
+
+// reloadLocked swaps in a freshly loaded general config, asking the
+// validators first if validate is set. c.writeMu has to be held.
+func (c *Config) reloadLocked(validate bool) error {
+	old := c.general()
+	if old == nil {
+		return &ConfigError{Op: "reload", Path: c.name, Err: ErrNotLoaded}
+	}
+	gf, err := newGeneralConfig(old.Name, old.Path, old.ext, c.readOnly, c.fs)
+	if err == nil {
+		err = c.importShared(gf)
+	}
+	if err != nil {
+		return err
+	}
+
+	oldVals, newVals := old.effectiveValues(), gf.effectiveValues()
+	var changes []KeyDiff
+	for _, k := range unionKeys(oldVals, newVals) {
+		if oldVals[k] != newVals[k] {
+			changes = append(changes, KeyDiff{Key: k, Old: oldVals[k], New: newVals[k]})
+		}
+	}
+
+	c.mu.RLock()
+	validators := c.validators
+	c.mu.RUnlock()
+	for _, ch := range changes {
+		for _, v := range validators {
+			if !validate || !keyMatches(v.pattern, ch.Key) {
+				continue
+			}
+			if err = v.fn(ch.Old, ch.New); err != nil {
+				return gf.keyError("reload", ch.Key, err)
+			}
+		}
+	}
+
+	c.setGeneral(gf)
+	c.mu.Lock()
+	c.addons = make(map[string]*AddonConfig)
+	c.mu.Unlock()
+	for _, ch := range changes {
+		c.notify(ch.Key, ch.Old, ch.New)
+	}
+	return nil
+}
+
+// Watch checks the config files every interval and calls Reload when one of
+// them has been modified. Reload errors, including rejected changes, are
+// passed to onError if it isn't nil. Call the returned func to stop.
+func (c *Config) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := c.filesStamp()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			stamp := c.filesStamp()
+			if stamp == last {
+				continue
+			}
+			last = stamp
+			if err := c.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// Close stops the goroutine that runs the OnChange callbacks, returning
+// once it's delivered everything already queued, so it mustn't be called
+// from a callback
+func (c *Config) Close() error {
+	c.mu.Lock()
+	events := c.events
+	c.events = nil
+	c.mu.Unlock()
+	if events != nil {
+		events.close()
+	}
+	return nil
+}
+
+// changed is the general config's onChange hook
+func (c *Config) changed(ch Change) {
+	dropped := c.recordChange(ch)
+	c.releaseBlobs(append(dropped, ch))
+	c.notify(ch.Key, ch.Old, ch.New)
+}
+
+// notify queues the OnChange callbacks matching k
+func (c *Config) notify(k, old, new string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, w := range c.watchers {
+		if keyMatches(w.pattern, k) && c.events != nil {
+			fn := w.fn
+			c.events.push(func() { fn(k, old, new) })
+		}
+	}
+}
+
+// filesStamp summarizes the modification times of the config files, so
+// Watch can tell when they change
+func (c *Config) filesStamp() string {
+	gf := c.general()
+	paths := []string{gf.GetFullPath(), gf.GetDropInDir()}
+	gf.mu.RLock()
+	for _, l := range append(append([]configLayer{}, gf.overlays...), gf.layers...) {
+		paths = append(paths, l.path)
+	}
+	gf.mu.RUnlock()
+	var stamp string
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil {
+			stamp += p + strconv.FormatInt(fi.ModTime().UnixNano(), 10) + strconv.FormatInt(fi.Size(), 10)
+		}
+	}
+	return stamp
+}
+
+// effectiveValues returns the unexpanded value of every key in gf and the
+// files it includes
+func (gf *GeneralConfig) effectiveValues() map[string]string {
+	gf.mu.RLock()
+	defer gf.mu.RUnlock()
+	ret := make(map[string]string)
+	for _, k := range gf.keyList() {
+		ret[k], _ = gf.lookupLocked(k)
+	}
+	return ret
+}
+
+// keyMatches returns whether k matches the glob pattern
+func keyMatches(pattern, k string) bool {
+	ok, err := path.Match(pattern, k)
+	return err == nil && ok
+}
+
+// dispatcher runs queued funcs in order on its own goroutine
+type dispatcher struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []func()
+	closed bool
+	done   chan struct{}
+}
+
+func newDispatcher() *dispatcher {
+	d := &dispatcher{done: make(chan struct{})}
+	d.cond = sync.NewCond(&d.mu)
+	go d.run()
+	return d
+}
+
+func (d *dispatcher) push(fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.queue = append(d.queue, fn)
+		d.cond.Signal()
+	}
+}
+
+// close stops accepting funcs and waits for the queued ones to run
+func (d *dispatcher) close() {
+	d.mu.Lock()
+	d.closed = true
+	d.cond.Signal()
+	d.mu.Unlock()
+	<-d.done
+}
+
+func (d *dispatcher) run() {
+	defer close(d.done)
+	for {
+		d.mu.Lock()
+		for len(d.queue) == 0 && !d.closed {
+			d.cond.Wait()
+		}
+		if len(d.queue) == 0 {
+			d.mu.Unlock()
+			return
+		}
+		fn := d.queue[0]
+		d.queue = d.queue[1:]
+		d.mu.Unlock()
+		fn()
+	}
+}
@@ -0,0 +1,69 @@
+package userConfig_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/br0xen/user-config/userconfigtest"
+)
+
+func TestOnChangeOrder(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	var got []string
+	c.OnChange("count", func(old, new string) {
+		got = append(got, new)
+	})
+	for i := 0; i < 100; i++ {
+		if err := c.Set("count", strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Close waits for the queued callbacks
+	c.Close()
+	if len(got) != 100 {
+		t.Fatalf("got %d changes, want 100", len(got))
+	}
+	for i, v := range got {
+		if v != strconv.Itoa(i) {
+			t.Fatalf("change %d was %q, want %d", i, v, i)
+		}
+	}
+}
+
+func TestSetDuringReload(t *testing.T) {
+	c := userconfigtest.New(t, "app")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := c.Set(k, strconv.Itoa(j)); err != nil {
+					t.Error(err)
+					return
+				}
+				c.Get(k)
+			}
+		}("k" + strconv.Itoa(i))
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			if err := c.Reload(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	for i := 0; i < 4; i++ {
+		k := "k" + strconv.Itoa(i)
+		if v := c.Get(k); v != "19" {
+			t.Errorf("%s = %q after the sets, want 19", k, v)
+		}
+		userconfigtest.AssertSaved(t, c, k, "19")
+	}
+}